@@ -1,98 +1,541 @@
 package configstruct
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
 )
 
+// ErrShutdownTimeout is returned if a command function did not return in time after its context was cancelled
+var ErrShutdownTimeout = errors.New("command did not shut down in time")
+
 // CommandFunc is a function that is executed when a command is referenced in a CLI call
 type CommandFunc func(c *Command, cfg interface{}) error
 
+// CommandFuncCtx is a CommandFunc that additionally gets the context of the current run
+type CommandFuncCtx func(ctx context.Context, c *Command, cfg interface{}) error
+
 // Command defines a command that consists of a name (empty for root command), a struct that models all
 // flags, a function that is executed if the command matches and that gets the config struct as argument
 // several sub-commands can be added
 type Command struct {
 	fs           *flag.FlagSet
+	description  string
 	config       interface{}
 	f            CommandFunc
 	subCommands  []*Command
 	rootCommand  *Command
 	dependencies map[string]interface{}
+	opts         []Option
+	ctx          context.Context
+
+	preRun            CommandFunc
+	postRun           CommandFunc
+	finally           CommandFunc
+	persistentPreRun  CommandFunc
+	persistentPostRun CommandFunc
+
+	middlewares []Middleware
+
+	out    io.Writer
+	errOut io.Writer
+
+	aliases []string
+	hidden  bool
+
+	longDescription string
+	examples        []string
+	group           string
+	helpTemplate    *template.Template
+
+	flagCompletions map[string]CompletionFunc
+	argCompletions  map[int]CompletionFunc
 }
 
 // NewCommand creates a command that is triggered by the given name in the command line
 // all flags are defined by a struct that is parsed and filled with real values
 // this struct is then set as argument for the function that is executed if the name matches
 func NewCommand(name string, description string, config interface{}, f CommandFunc, subCommands ...*Command) *Command {
-	fs := flag.NewFlagSet(name, flag.ExitOnError)
-	fs.Usage = func() {
-		fmt.Fprint(fs.Output(), description+"\n\n")
-		if name == "" {
-			fmt.Fprintf(fs.Output(), "Usage:\n")
-		} else {
-			fmt.Fprintf(fs.Output(), "Usage of %s:\n", name)
-		}
-		fs.PrintDefaults()
-
-		if len(subCommands) > 0 {
-			fmt.Fprintf(fs.Output(), "\nAvailable Commands:\n")
-			for i := range subCommands {
-				fmt.Fprintf(fs.Output(), subCommands[i].fs.Name()+" ")
-			}
-			fmt.Fprintf(fs.Output(), "\n")
-		}
-	}
-
-	return &Command{
-		fs:           fs,
+	c := &Command{
+		fs:           flag.NewFlagSet(name, flag.ContinueOnError),
+		description:  description,
 		config:       config,
 		f:            f,
 		subCommands:  subCommands,
 		dependencies: make(map[string]interface{}),
 	}
+	c.fs.Usage = func() {
+		c.printUsage(c.fs.Output())
+	}
+
+	for i := range subCommands {
+		subCommands[i].rootCommand = c
+	}
+
+	return c
+}
+
+// SetAliases sets alternative names the command can be referenced by
+func (c *Command) SetAliases(aliases ...string) *Command {
+	c.aliases = aliases
+	return c
+}
+
+// SetHidden hides the command from the list of available commands, it can still be called
+func (c *Command) SetHidden(hidden bool) *Command {
+	c.hidden = hidden
+	return c
+}
+
+// SetOutput sets the writer for regular output like help, it is used by all sub-commands that don't set their own.
+// Defaults to os.Stdout
+func (c *Command) SetOutput(w io.Writer) *Command {
+	c.out = w
+	return c
+}
+
+// SetErrOutput sets the writer for error output, it is used by all sub-commands that don't set their own.
+// Defaults to os.Stderr
+func (c *Command) SetErrOutput(w io.Writer) *Command {
+	c.errOut = w
+	return c
+}
+
+// Output returns the writer for regular output of this command
+func (c *Command) Output() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		if cmd.out != nil {
+			return cmd.out
+		}
+	}
+
+	return os.Stdout
+}
+
+// ErrOutput returns the writer for error output of this command
+func (c *Command) ErrOutput() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		if cmd.errOut != nil {
+			return cmd.errOut
+		}
+	}
+
+	return os.Stderr
+}
+
+// NewCommandContext creates a command like NewCommand but with a function that gets the context of the current run,
+// use it together with ParseAndRunContext to cancel long-running commands
+func NewCommandContext(name string, description string, config interface{}, f CommandFuncCtx, subCommands ...*Command) *Command {
+	var cmdFunc CommandFunc
+	if f != nil {
+		cmdFunc = func(c *Command, cfg interface{}) error {
+			return f(c.Context(), c, cfg)
+		}
+	}
+
+	return NewCommand(name, description, config, cmdFunc, subCommands...)
 }
 
-// ParseAndRun parses the given arguments and executes command functions
+// Context returns the context of the current run, it is cancelled on SIGINT or SIGTERM if the
+// command was started with ParseAndRunContext
+func (c *Command) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// SetOptions attaches options to this command only, they are applied after the options
+// inherited from parent commands and therefore override them
+func (c *Command) SetOptions(opts ...Option) *Command {
+	c.opts = opts
+	return c
+}
+
+// ParseAndRun parses the given arguments and executes command functions,
+// the given options are passed down to all sub-commands
 func (c *Command) ParseAndRun(args []string, opts ...Option) error {
-	err := ParseWithFlagSet(c.fs, args, c.config, opts...)
+	return c.parseAndRun(context.Background(), args, opts)
+}
+
+// ParseAndRunContext works like ParseAndRun but passes a context to all commands in the chain
+// that is cancelled if the process receives SIGINT or SIGTERM, a second signal terminates the process
+func (c *Command) ParseAndRunContext(ctx context.Context, args []string, opts ...Option) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// after the first signal the default behaviour is restored, so a second signal terminates a hung command
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return c.parseAndRun(ctx, args, opts)
+}
+
+// parsedCommand is a command of the executed path together with its options and arguments
+type parsedCommand struct {
+	cmd  *Command
+	cfg  config
+	args []string
+}
+
+func (c *Command) parseAndRun(ctx context.Context, args []string, opts []Option) error {
+	// the hidden completion command is handled by the root before any flags are parsed
+	if c.rootCommand == nil && len(args) > 1 && args[1] == completeCommandName {
+		return c.runCompleteCommand(args[2:])
+	}
+
+	// the whole command path is parsed before any hook or function runs,
+	// so persistent flags given after a sub-command are already set for all parents
+	path := make([]parsedCommand, 0)
+	for cmd, cmdArgs := c, args; cmd != nil; {
+		cfg, err := cmd.parse(ctx, cmdArgs, opts)
+		if err != nil {
+			return err
+		}
+		path = append(path, parsedCommand{cmd: cmd, cfg: cfg, args: cmdArgs})
+
+		if cmd.isHelpCommand(cmd.fs.Args(), cfg.prefixMatching) {
+			return cmd.runHelpCommand(cmd.fs.Args()[1:], cfg.prefixMatching)
+		}
+
+		subCmd, err := cmd.parsedSubCommand(cfg)
+		if err != nil {
+			return err
+		}
+		if subCmd != nil {
+			subCmd.rootCommand = cmd
+		}
+		cmd, cmdArgs = subCmd, cmd.fs.Args()
+	}
+
+	// persistent flags of sub-commands must not override env values of parents with env precedence
+	for _, p := range path[:len(path)-1] {
+		if p.cfg.precedenceEnv && p.cmd.config != nil {
+			if err := applyEnv(p.cmd.config, p.cfg); err != nil {
+				return err
+			}
+		}
+	}
+
+	return runPath(path, 0)
+}
+
+// parse parses the flags and arguments of this command and returns its effective options
+func (c *Command) parse(ctx context.Context, args []string, opts []Option) (config, error) {
+	c.ctx = ctx
+
+	cmdOpts := make([]Option, 0, len(opts)+len(c.opts)+1)
+	cmdOpts = append(cmdOpts, opts...)
+	// a config file that is not set for this command only is shared with the whole command tree
+	if newConfig(c.opts...).file == "" {
+		cmdOpts = append(cmdOpts, withSharedConfigs(c.sharedConfigs()))
+	}
+	cmdOpts = append(cmdOpts, c.opts...)
+
+	// persistent flags of all parent commands are also accepted by this command, its own flags shadow them
+	ownFlags := structFlagNames(c.config)
+	hiddenFlags := make(map[string]bool)
+	for parent := c.rootCommand; parent != nil; parent = parent.rootCommand {
+		if parent.config == nil {
+			continue
+		}
+		for name := range hiddenFlagNames(parent.config, true) {
+			if !ownFlags[name] && c.fs.Lookup(name) == nil {
+				hiddenFlags[name] = true
+			}
+		}
+		if err := defineFlags(c.fs, parent.config, true, ownFlags); err != nil {
+			return config{}, err
+		}
+	}
+	cmdOpts = append(cmdOpts, withHiddenFlags(hiddenFlags))
+
+	// the flag package would print the usage to the FlagSet output, it is printed here to the right writer instead
+	usage := c.fs.Usage
+	c.fs.Usage = func() {}
+	c.fs.SetOutput(c.ErrOutput())
+	err := ParseWithFlagSet(c.fs, args, c.config, cmdOpts...)
+	c.fs.Usage = usage
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			c.printUsage(c.Output())
+			return config{}, &HelpError{Command: c.fs.Name()}
+		}
+
+		var usageErr *UsageError
+		if errors.As(err, &usageErr) {
+			var unknownFlagErr *UnknownFlagError
+			if errors.As(err, &unknownFlagErr) && len(unknownFlagErr.Suggestions) > 0 {
+				fmt.Fprintf(c.ErrOutput(), "%s\n\n", didYouMean(unknownFlagErr.Suggestions, "-"))
+			}
+			c.printUsage(c.ErrOutput())
+		}
+
+		return config{}, err
+	}
+
+	return newConfig(cmdOpts...), nil
+}
+
+// parsedSubCommand returns the sub-command referenced by the remaining arguments of a parsed command,
+// nil if there is none or an *UnknownCommandError
+func (c *Command) parsedSubCommand(cfg config) (*Command, error) {
+	cmdArgs := c.fs.Args()
+	if len(c.subCommands) == 0 || len(cmdArgs) == 0 {
+		return nil, nil
+	}
+
+	subCmd := c.findSubCommand(cmdArgs, cfg.prefixMatching)
+	if subCmd == nil {
+		unknownErr := &UnknownCommandError{
+			Name:        cmdArgs[0],
+			Available:   c.subCommandNames(),
+			Suggestions: suggest(cmdArgs[0], c.subCommandNames()),
+		}
+		fmt.Fprintf(c.ErrOutput(), "Command '%s' not defined\n", cmdArgs[0])
+		if len(unknownErr.Suggestions) > 0 {
+			fmt.Fprintln(c.ErrOutput(), didYouMean(unknownErr.Suggestions, ""))
+		}
+		fmt.Fprintln(c.ErrOutput())
+		c.printUsage(c.ErrOutput())
+		return nil, unknownErr
+	}
+
+	return subCmd, nil
+}
+
+// runPath runs the hooks and the function of the parsed command at index i followed by the rest of the path
+func runPath(path []parsedCommand, i int) (err error) {
+	c, cfg := path[i].cmd, path[i].cfg
+	leaf := i == len(path)-1
+
+	// the finally hook runs as soon as the command was parsed, even if the command or a sub-command failed
+	if c.finally != nil {
+		defer func() {
+			if finallyErr := c.finally(c, c.config); finallyErr != nil && err == nil {
+				err = finallyErr
+			}
+		}()
+	}
+
+	// persistent hooks of all parents run once around the executed leaf command
+	err = c.runPreHooks(leaf)
 	if err != nil {
 		return err
 	}
 
-	if c.f != nil && (c.fs.Name() == "" || strings.EqualFold(c.fs.Name(), args[0])) {
-		err := c.f(c, c.config)
+	// in leaf only mode the function only runs if no sub-command follows
+	runFunc := !cfg.leafOnly || leaf
+	// sub-commands were already matched by name or alias by their parent
+	if runFunc && c.f != nil && (i > 0 || c.rootCommand != nil || c.fs.Name() == "" || strings.EqualFold(c.fs.Name(), path[i].args[0])) {
+		err := c.execute(cfg.shutdownTimeout)
 		if err != nil {
 			return err
 		}
 	}
 
-	args = c.fs.Args()
-	if len(c.subCommands) > 0 && len(args) == 0 {
-		c.fs.Usage()
+	if leaf && len(c.subCommands) > 0 {
+		if !cfg.leafOnly || c.f == nil {
+			c.printUsage(c.Output())
+		}
+	}
+
+	if !leaf {
+		if err := runPath(path, i+1); err != nil {
+			return err
+		}
+	}
+
+	return c.runPostHooks(leaf)
+}
+
+// sharedConfigs returns the config structs of all commands in the tree that don't set their own config file
+func (c *Command) sharedConfigs() []interface{} {
+	root := c
+	for root.rootCommand != nil {
+		root = root.rootCommand
+	}
+
+	configs := make([]interface{}, 0)
+	var collect func(cmd *Command)
+	collect = func(cmd *Command) {
+		if cmd.config != nil && newConfig(cmd.opts...).file == "" {
+			configs = append(configs, cmd.config)
+		}
+		for _, sub := range cmd.subCommands {
+			collect(sub)
+		}
+	}
+	collect(root)
+
+	return configs
+}
+
+// subCommandNames returns the names of all sub-commands that are not hidden
+func (c *Command) subCommandNames() []string {
+	names := make([]string, 0, len(c.subCommands))
+	for i := range c.subCommands {
+		if !c.subCommands[i].hidden {
+			names = append(names, c.subCommands[i].fs.Name())
+		}
+	}
+
+	return names
+}
+
+// findSubCommand returns the sub-command referenced by its name or an alias in the first argument or nil,
+// if prefixMatching is set a unique prefix of a name or alias is sufficient
+func (c *Command) findSubCommand(args []string, prefixMatching bool) *Command {
+	if len(args) == 0 {
 		return nil
 	}
 
-	if len(args) > 0 {
-		cmdFound := false
-		for i := range c.subCommands {
-			if strings.EqualFold(c.subCommands[i].fs.Name(), args[0]) {
-				c.subCommands[i].rootCommand = c
-				cmdFound = true
-				if err := c.subCommands[i].ParseAndRun(args); err != nil {
-					return err
+	var prefixMatch *Command
+	prefixMatches := 0
+	for i := range c.subCommands {
+		sub := c.subCommands[i]
+		names := append([]string{sub.fs.Name()}, sub.aliases...)
+		for _, name := range names {
+			if strings.EqualFold(name, args[0]) {
+				return sub
+			}
+		}
+
+		// hidden commands are only found by their full name or an alias
+		if prefixMatching && args[0] != "" && !sub.hidden {
+			for _, name := range names {
+				if strings.HasPrefix(strings.ToLower(name), strings.ToLower(args[0])) {
+					if prefixMatch != sub {
+						prefixMatches++
+					}
+					prefixMatch = sub
 				}
 			}
 		}
-		if !cmdFound {
-			fmt.Fprintf(c.fs.Output(), "Command '%s' not defined\n\n", args[0])
-			c.fs.Usage()
+	}
+
+	if prefixMatches == 1 {
+		return prefixMatch
+	}
+
+	return nil
+}
+
+// runPreHooks runs the persistent pre-run hooks from the outermost parent down to this command if persistent is set,
+// followed by the pre-run hook of this command
+func (c *Command) runPreHooks(persistent bool) error {
+	chain := make([]*Command, 0)
+	for cmd := c; cmd != nil && persistent; cmd = cmd.rootCommand {
+		chain = append([]*Command{cmd}, chain...)
+	}
+
+	for _, cmd := range chain {
+		if cmd.persistentPreRun != nil {
+			if err := cmd.persistentPreRun(c, c.config); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.preRun != nil {
+		return c.preRun(c, c.config)
+	}
+
+	return nil
+}
+
+// runPostHooks runs the post-run hook of this command followed by the persistent post-run hooks
+// from this command up to the outermost parent if persistent is set
+func (c *Command) runPostHooks(persistent bool) error {
+	if c.postRun != nil {
+		if err := c.postRun(c, c.config); err != nil {
+			return err
+		}
+	}
+
+	for cmd := c; cmd != nil && persistent; cmd = cmd.rootCommand {
+		if cmd.persistentPostRun != nil {
+			if err := cmd.persistentPostRun(c, c.config); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// execute runs the command function, if the context gets cancelled the function has the given timeout to return
+func (c *Command) execute(timeout time.Duration) error {
+	f := c.handler()
+	if timeout <= 0 {
+		return f(c, c.config)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f(c, c.config)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.Context().Done():
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return ErrShutdownTimeout
+	}
+}
+
+// SetPreRun sets a hook that runs before the command function
+func (c *Command) SetPreRun(f CommandFunc) *Command {
+	c.preRun = f
+	return c
+}
+
+// SetPostRun sets a hook that runs after the command function and all sub-commands finished without error
+func (c *Command) SetPostRun(f CommandFunc) *Command {
+	c.postRun = f
+	return c
+}
+
+// SetFinally sets a hook that always runs after the command and all sub-commands finished, even on error
+func (c *Command) SetFinally(f CommandFunc) *Command {
+	c.finally = f
+	return c
+}
+
+// SetPersistentPreRun sets a hook that runs once before the pre-run hook of the executed leaf command,
+// the hook gets the leaf command as argument
+func (c *Command) SetPersistentPreRun(f CommandFunc) *Command {
+	c.persistentPreRun = f
+	return c
+}
+
+// SetPersistentPostRun sets a hook that runs once after the post-run hook of the executed leaf command,
+// the hook gets the leaf command as argument
+func (c *Command) SetPersistentPostRun(f CommandFunc) *Command {
+	c.persistentPostRun = f
+	return c
+}
+
 // SetDependency saves a dependency referenced by a name for subcommands
 func (c *Command) SetDependency(name string, dep interface{}) {
 	c.dependencies[name] = dep
@@ -113,9 +556,9 @@ func (c *Command) GetDependency(name string) (interface{}, error) {
 }
 
 // Save writes the current command config to a YAML file
-func (c *Command) Save(path string) error {
+func (c *Command) Save(path string, opts ...SaveOption) error {
 	if c.config == nil {
 		return fmt.Errorf("no config defined for this command")
 	}
-	return Save(path, c.config)
+	return Save(path, c.config, opts...)
 }
@@ -1,8 +1,15 @@
 package configstruct
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -48,6 +55,430 @@ func TestCommand_ParseAndRun(t *testing.T) {
 	}
 }
 
+func TestCommand_Options(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CONFIGSTRUCT_NUMBER", "5")
+	os.Setenv("SUB_NUMBER", "7")
+	defer os.Clearenv()
+
+	type numberConfig struct {
+		Number int `env:"NUMBER" cli:"number" usage:"number to count"`
+	}
+
+	t.Run("inherited by sub-commands", func(t *testing.T) {
+		var rootConfig, countConfig numberConfig
+
+		countCmd := NewCommand("count", "Count numbers", &countConfig, nil)
+		cmd := NewCommand("", "Test CLI", &rootConfig, nil, countCmd)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count", "-number=2"}, WithEnvPrefix("CONFIGSTRUCT_"), WithPrecedenceEnv())
+		assert.NoError(t, err)
+		assert.Equal(t, 5, rootConfig.Number)
+		assert.Equal(t, 5, countConfig.Number)
+	})
+
+	t.Run("command options override inherited", func(t *testing.T) {
+		var rootConfig, countConfig numberConfig
+
+		countCmd := NewCommand("count", "Count numbers", &countConfig, nil).SetOptions(WithEnvPrefix("SUB_"))
+		cmd := NewCommand("", "Test CLI", &rootConfig, nil, countCmd)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count"}, WithEnvPrefix("CONFIGSTRUCT_"))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, rootConfig.Number)
+		assert.Equal(t, 7, countConfig.Number)
+	})
+}
+
+func TestCommand_StrictSharedConfig(t *testing.T) {
+	os.Clearenv()
+
+	type rootConfig struct {
+		Hostname string `cli:"hostname" yaml:"hostname"`
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("hostname: localhost\nnumber: 2\n"), 0600)
+	assert.NoError(t, err)
+
+	t.Run("keys of all commands are known", func(t *testing.T) {
+		var rootCfg rootConfig
+		var countConfig subCmdConfig
+
+		countCmd := NewCommand("count", "Count numbers", &countConfig, nil)
+		cmd := NewCommand("", "Test CLI", &rootCfg, nil, countCmd, NewConfigCommand(&rootCfg))
+
+		err := cmd.ParseAndRun([]string{"cliName", "count"}, WithYamlConfig(path), WithStrictConfig())
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", rootCfg.Hostname)
+		assert.Equal(t, 2, countConfig.Number)
+	})
+
+	t.Run("unknown keys are rejected", func(t *testing.T) {
+		var rootCfg rootConfig
+
+		cmd := NewCommand("", "Test CLI", &rootCfg, nil, NewConfigCommand(&rootCfg))
+
+		err := cmd.ParseAndRun([]string{"cliName"}, WithYamlConfig(path), WithStrictConfig())
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "number", validationErr.Issues[0].Path)
+	})
+
+	t.Run("own config file", func(t *testing.T) {
+		var rootCfg rootConfig
+		var countConfig subCmdConfig
+
+		countCmd := NewCommand("count", "Count numbers", &countConfig, nil).SetOptions(WithYamlConfig(path))
+		cmd := NewCommand("", "Test CLI", &rootCfg, nil, countCmd)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count"}, WithStrictConfig())
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "hostname", validationErr.Issues[0].Path)
+	})
+}
+
+func TestCommand_PersistentFlags(t *testing.T) {
+	os.Clearenv()
+
+	type persistentConfig struct {
+		Hostname string `cli:"hostname" persistent:"true" usage:"hostname value"`
+		Debug    bool   `cli:"debug" usage:"debug mode"`
+	}
+
+	var rootConfig persistentConfig
+	var countConfig subCmdConfig
+
+	countCmd := NewCommand("count", "Count numbers", &countConfig, nil)
+	mathCmd := NewCommand("math", "Mathematical functions", nil, nil, countCmd)
+	cmd := NewCommand("", "Test CLI", &rootConfig, nil, mathCmd)
+
+	err := cmd.ParseAndRun([]string{"cliName", "math", "count", "-number", "2", "-hostname=remote"})
+	assert.NoError(t, err)
+	assert.Equal(t, "remote", rootConfig.Hostname)
+	assert.Equal(t, 2, countConfig.Number)
+	assert.Nil(t, countCmd.fs.Lookup("debug"))
+}
+
+func TestCommand_PersistentFlagsBeforeRun(t *testing.T) {
+	os.Clearenv()
+
+	type rootConfig struct {
+		Hostname string `env:"HOST" cli:"hostname" persistent:"true" usage:"hostname value"`
+	}
+
+	t.Run("parents see persistent flags of sub-commands", func(t *testing.T) {
+		var rootCfg rootConfig
+		seen := make([]string, 0)
+		record := func(cmd *Command, cfg interface{}) error {
+			seen = append(seen, rootCfg.Hostname)
+			return nil
+		}
+
+		countCmd := NewCommand("count", "Count numbers", &subCmdConfig{}, record)
+		cmd := NewCommand("", "Test CLI", &rootCfg, record, countCmd).SetPreRun(record)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count", "-hostname=db.example"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"db.example", "db.example", "db.example"}, seen)
+	})
+
+	t.Run("env precedence", func(t *testing.T) {
+		os.Setenv("HOST", "fromenv")
+		defer os.Unsetenv("HOST")
+
+		for _, args := range [][]string{
+			{"cliName", "-hostname=cli", "count"},
+			{"cliName", "count", "-hostname=cli"},
+		} {
+			var rootCfg rootConfig
+			cmd := NewCommand("", "Test CLI", &rootCfg, nil, NewCommand("count", "Count numbers", &subCmdConfig{}, nil))
+
+			err := cmd.ParseAndRun(args, WithPrecedenceEnv())
+			assert.NoError(t, err)
+			assert.Equal(t, "fromenv", rootCfg.Hostname, args)
+		}
+	})
+}
+
+func TestCommand_PersistentFlagsShadowed(t *testing.T) {
+	os.Clearenv()
+
+	type rootConfig struct {
+		Hostname string `cli:"hostname" cliAlt:"H" persistent:"true" usage:"hostname value"`
+		Debug    bool   `cli:"debug" persistent:"true" usage:"debug mode"`
+	}
+	type subConfig struct {
+		Hostname string `cli:"hostname" usage:"remote hostname"`
+		Debug    bool   `cli:"debug" usage:"debug sub-command"`
+	}
+
+	var rootCfg rootConfig
+	var subCfg subConfig
+
+	subCmd := NewCommand("sub", "Sub command", &subCfg, nil)
+	cmd := NewCommand("", "Test CLI", &rootCfg, nil, subCmd)
+
+	err := cmd.ParseAndRun([]string{"cliName", "sub", "-hostname=remote", "-debug", "-H=inherited"})
+	assert.NoError(t, err)
+	assert.Equal(t, "remote", subCfg.Hostname)
+	assert.True(t, subCfg.Debug)
+	assert.Equal(t, "inherited", rootCfg.Hostname)
+	assert.False(t, rootCfg.Debug)
+}
+
+func TestCommand_ParseAndRunContext(t *testing.T) {
+	t.Run("context is passed to all commands", func(t *testing.T) {
+		var countConfig subCmdConfig
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		countCmd := NewCommandContext("count", "Count numbers", &countConfig, func(ctx context.Context, cmd *Command, cfg interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		cmd := NewCommandContext("", "Test CLI", nil, func(ctx context.Context, cmd *Command, cfg interface{}) error {
+			cancel()
+			return nil
+		}, countCmd)
+
+		err := cmd.ParseAndRunContext(ctx, []string{"cliName", "count"})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("shutdown timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		cmd := NewCommand("", "Test CLI", nil, func(cmd *Command, cfg interface{}) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+
+		err := cmd.ParseAndRunContext(ctx, []string{"cliName"}, WithShutdownTimeout(10*time.Millisecond))
+		assert.ErrorIs(t, err, ErrShutdownTimeout)
+	})
+}
+
+func TestCommand_Hooks(t *testing.T) {
+	calls := make([]string, 0)
+	record := func(name string) CommandFunc {
+		return func(cmd *Command, cfg interface{}) error {
+			calls = append(calls, name+":"+cmd.fs.Name())
+			return nil
+		}
+	}
+
+	var countConfig subCmdConfig
+	countCmd := NewCommand("count", "Count numbers", &countConfig, record("run")).
+		SetPreRun(record("pre")).
+		SetPostRun(record("post"))
+	cmd := NewCommand("", "Test CLI", nil, record("run"), countCmd).
+		SetPersistentPreRun(record("persistentPre")).
+		SetPersistentPostRun(record("persistentPost")).
+		SetFinally(record("finally"))
+
+	err := cmd.ParseAndRun([]string{"cliName", "count"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"run:",
+		"persistentPre:count", "pre:count", "run:count", "post:count", "persistentPost:count",
+		"finally:",
+	}, calls)
+
+	t.Run("persistent hooks run once", func(t *testing.T) {
+		calls = calls[:0]
+		leafCmd := NewCommand("leaf", "Leaf", nil, record("run"))
+		subCmd := NewCommand("sub", "Sub", nil, nil, leafCmd).
+			SetPersistentPostRun(record("subPersistentPost"))
+		cmd := NewCommand("", "Test CLI", nil, nil, subCmd).
+			SetPersistentPreRun(record("persistentPre")).
+			SetPersistentPostRun(record("persistentPost"))
+
+		err := cmd.ParseAndRun([]string{"cliName", "sub", "leaf"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"persistentPre:leaf", "run:leaf", "subPersistentPost:leaf", "persistentPost:leaf"}, calls)
+	})
+
+	t.Run("finally runs on error", func(t *testing.T) {
+		calls = calls[:0]
+		failCmd := NewCommand("fail", "Fail", nil, func(cmd *Command, cfg interface{}) error {
+			return errors.New("failed")
+		})
+		cmd := NewCommand("", "Test CLI", nil, nil, failCmd).
+			SetPostRun(record("post")).
+			SetFinally(record("finally"))
+
+		err := cmd.ParseAndRun([]string{"cliName", "fail"})
+		assert.EqualError(t, err, "failed")
+		assert.Equal(t, []string{"finally:"}, calls)
+	})
+}
+
+func TestCommand_RunLeafOnly(t *testing.T) {
+	calls := make([]string, 0)
+	record := func(cmd *Command, cfg interface{}) error {
+		calls = append(calls, cmd.fs.Name())
+		return nil
+	}
+
+	var countConfig subCmdConfig
+	countCmd := NewCommand("count", "Count numbers", &countConfig, record)
+	mathCmd := NewCommand("math", "Mathematical functions", nil, record, countCmd)
+	cmd := NewCommand("", "Test CLI", nil, record, mathCmd)
+
+	err := cmd.ParseAndRun([]string{"cliName", "math", "count", "-number", "2"}, WithRunLeafOnly())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"count"}, calls)
+	assert.Equal(t, 2, countConfig.Number)
+
+	calls = calls[:0]
+	err = cmd.ParseAndRun([]string{"cliName", "math"}, WithRunLeafOnly())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"math"}, calls)
+}
+
+func TestCommand_Output(t *testing.T) {
+	t.Run("help is written to output", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		var countConfig subCmdConfig
+
+		countCmd := NewCommand("count", "Count numbers", &countConfig, nil)
+		cmd := NewCommand("", "Test CLI", nil, nil, countCmd).SetOutput(&out).SetErrOutput(&errOut)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count", "-h"})
+		assert.ErrorIs(t, err, flag.ErrHelp)
+		assert.Equal(t, ExitCodeOK, ExitCode(err))
+		assert.Contains(t, out.String(), "Count numbers")
+		assert.Contains(t, out.String(), "number to count")
+		assert.Empty(t, errOut.String())
+	})
+
+	t.Run("errors are written to error output", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		var countConfig subCmdConfig
+
+		cmd := NewCommand("count", "Count numbers", &countConfig, nil).SetOutput(&out).SetErrOutput(&errOut)
+
+		err := cmd.ParseAndRun([]string{"count", "-undefined"})
+		assert.Error(t, err)
+		assert.Equal(t, ExitCodeUsage, ExitCode(err))
+		assert.Contains(t, errOut.String(), "flag provided but not defined: -undefined")
+		assert.Contains(t, errOut.String(), "Count numbers")
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("command without config", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		ran := false
+
+		cmd := NewCommand("math", "Mathematical functions", nil, func(c *Command, cfg interface{}) error {
+			ran = true
+			return nil
+		}).SetOutput(&out).SetErrOutput(&errOut)
+
+		err := cmd.ParseAndRun([]string{"math", "-bogus"})
+		var unknownErr *UnknownFlagError
+		assert.True(t, errors.As(err, &unknownErr))
+		assert.Equal(t, ExitCodeUsage, ExitCode(err))
+		assert.Contains(t, errOut.String(), "Mathematical functions")
+
+		err = cmd.ParseAndRun([]string{"math", "-h"})
+		var helpErr *HelpError
+		assert.True(t, errors.As(err, &helpErr))
+		assert.Contains(t, out.String(), "Mathematical functions")
+		assert.False(t, ran)
+	})
+}
+
+func TestCommand_Aliases(t *testing.T) {
+	calls := make([]string, 0)
+	record := func(cmd *Command, cfg interface{}) error {
+		calls = append(calls, cmd.fs.Name())
+		return nil
+	}
+
+	newCmd := func() *Command {
+		removeCmd := NewCommand("remove", "Remove entry", nil, record).SetAliases("rm")
+		countCmd := NewCommand("count", "Count numbers", nil, record)
+		cloneCmd := NewCommand("clone", "Clone entry", nil, record)
+		return NewCommand("", "Test CLI", nil, nil, removeCmd, countCmd, cloneCmd).SetErrOutput(&bytes.Buffer{})
+	}
+
+	err := newCmd().ParseAndRun([]string{"cliName", "rm"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"remove"}, calls)
+
+	calls = calls[:0]
+	err = newCmd().ParseAndRun([]string{"cliName", "cou"}, WithPrefixMatching())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"count"}, calls)
+
+	calls = calls[:0]
+	err = newCmd().ParseAndRun([]string{"cliName", "c"}, WithPrefixMatching())
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	assert.Empty(t, calls)
+
+	err = newCmd().ParseAndRun([]string{"cliName", "cou"})
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
+
+func TestCommand_Hidden(t *testing.T) {
+	type hiddenConfig struct {
+		Hostname string `cli:"hostname" usage:"hostname value"`
+		Debug    bool   `cli:"debug" hidden:"true" usage:"internal debug mode"`
+	}
+
+	var out bytes.Buffer
+	var conf hiddenConfig
+
+	debugCmd := NewCommand("debug", "Debug internals", nil, nil).SetHidden(true)
+	countCmd := NewCommand("count", "Count numbers", nil, nil)
+	cmd := NewCommand("", "Test CLI", &conf, nil, debugCmd, countCmd).SetOutput(&out)
+
+	err := cmd.ParseAndRun([]string{"cliName", "-debug", "-h"})
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.Contains(t, out.String(), "hostname value")
+	assert.NotContains(t, out.String(), "internal debug mode")
+	assert.NotContains(t, out.String(), "no-debug")
+	assert.Contains(t, out.String(), "count")
+	assert.NotContains(t, out.String(), "debug ")
+
+	err = NewCommand("", "Test CLI", &hiddenConfig{}, nil, debugCmd).ParseAndRun([]string{"cliName", "-debug", "debug"})
+	assert.NoError(t, err)
+
+	t.Run("hidden commands need the full name", func(t *testing.T) {
+		called := false
+		debugCmd := NewCommand("debug", "Debug internals", nil, func(c *Command, cfg interface{}) error {
+			called = true
+			return nil
+		}).SetHidden(true)
+		cmd := NewCommand("", "Test CLI", nil, nil, debugCmd).SetOutput(io.Discard)
+
+		err := cmd.ParseAndRun([]string{"cliName", "deb"}, WithPrefixMatching())
+		assert.ErrorIs(t, err, ErrUnknownCommand)
+		assert.False(t, called)
+	})
+
+	t.Run("hidden persistent flags are not suggested", func(t *testing.T) {
+		type rootConfig struct {
+			Debug bool `cli:"debug" hidden:"true" persistent:"true"`
+		}
+
+		var errOut bytes.Buffer
+		countCmd := NewCommand("count", "Count numbers", &subCmdConfig{}, nil)
+		cmd := NewCommand("", "Test CLI", &rootConfig{}, nil, countCmd).SetOutput(io.Discard).SetErrOutput(&errOut)
+
+		err := cmd.ParseAndRun([]string{"cliName", "count", "-debu"})
+
+		var unknownErr *UnknownFlagError
+		assert.True(t, errors.As(err, &unknownErr))
+		assert.Empty(t, unknownErr.Suggestions)
+		assert.NotContains(t, errOut.String(), "did you mean")
+	})
+}
+
 func TestCommand_Dependencies(t *testing.T) {
 	c := NewCommand("testCmd", "Test CLI", nil, nil)
 
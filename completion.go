@@ -0,0 +1,321 @@
+package configstruct
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// completeCommandName is the name of the hidden command that is called by the completion scripts
+const completeCommandName = "__complete"
+
+// completion directives that are written as last line by the __complete command
+const (
+	completionNoFile = ":nofile"
+	completionFile   = ":file"
+)
+
+// CompletionFunc returns completion candidates for a flag value or argument that start with toComplete
+type CompletionFunc func(toComplete string) []string
+
+// RegisterFlagCompletion registers a function that returns completion candidates for the value of a flag
+func (c *Command) RegisterFlagCompletion(name string, f CompletionFunc) *Command {
+	if c.flagCompletions == nil {
+		c.flagCompletions = make(map[string]CompletionFunc)
+	}
+	c.flagCompletions[name] = f
+	return c
+}
+
+// RegisterArgCompletion registers a function that returns completion candidates for the positional argument
+// at the given position (starting with 1)
+func (c *Command) RegisterArgCompletion(position int, f CompletionFunc) *Command {
+	if c.argCompletions == nil {
+		c.argCompletions = make(map[int]CompletionFunc)
+	}
+	c.argCompletions[position] = f
+	return c
+}
+
+// GenCompletion writes the completion script for the given shell (bash, zsh or fish) to w
+func (c *Command) GenCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return c.GenBashCompletion(w)
+	case "zsh":
+		return c.GenZshCompletion(w)
+	case "fish":
+		return c.GenFishCompletion(w)
+	}
+
+	return fmt.Errorf("completion for shell %s not supported", shell)
+}
+
+// GenBashCompletion writes a bash completion script to w
+func (c *Command) GenBashCompletion(w io.Writer) error {
+	name := c.completionName()
+	_, err := fmt.Fprintf(w, `# bash completion for %[1]s
+_%[2]s_complete() {
+    local IFS=$'\n'
+    local cur words cword out directive
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur="${COMP_WORDS[COMP_CWORD]}"
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    fi
+
+    out=($("${words[0]}" %[3]s "${words[@]:1:cword}" 2>/dev/null))
+    directive="${out[${#out[@]}-1]}"
+    unset 'out[${#out[@]}-1]'
+
+    # bash only replaces the part after = of -flag=value
+    if [[ "$cur" == *=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        out=("${out[@]#"${cur%%%%=*}="}")
+        cur="${cur#*=}"
+    fi
+
+    COMPREPLY=("${out[@]}")
+    if [[ "$directive" == "%[4]s" ]]; then
+        COMPREPLY+=($(compgen -f -- "$cur"))
+    fi
+}
+complete -F _%[2]s_complete %[1]s
+`, name, shellIdentifier(name), completeCommandName, completionFile)
+
+	return err
+}
+
+// GenZshCompletion writes a zsh completion script to w
+func (c *Command) GenZshCompletion(w io.Writer) error {
+	name := c.completionName()
+	_, err := fmt.Fprintf(w, `#compdef %[1]s
+_%[2]s() {
+    local -a out
+    local directive
+    out=("${(@f)$(${words[1]} %[3]s "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    directive=${out[-1]}
+    out=("${(@)out[1,-2]}")
+
+    if [[ "$directive" == "%[4]s" ]]; then
+        _files
+    fi
+    compadd -- "${out[@]}"
+}
+compdef _%[2]s %[1]s
+`, name, shellIdentifier(name), completeCommandName, completionFile)
+
+	return err
+}
+
+// GenFishCompletion writes a fish completion script to w
+func (c *Command) GenFishCompletion(w io.Writer) error {
+	name := c.completionName()
+	_, err := fmt.Fprintf(w, `# fish completion for %[1]s
+function __%[2]s_complete
+    set -l args (commandline -opc) (commandline -ct)
+    set -l out ($args[1] %[3]s $args[2..-1] 2>/dev/null)
+    set -l directive $out[-1]
+    set -e out[-1]
+
+    for candidate in $out
+        echo $candidate
+    end
+    if test "$directive" = "%[4]s"
+        __fish_complete_path (commandline -ct)
+    end
+end
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`, name, shellIdentifier(name), completeCommandName, completionFile)
+
+	return err
+}
+
+// completionName returns the name of the executable the completion is registered for
+func (c *Command) completionName() string {
+	root := c
+	for root.rootCommand != nil {
+		root = root.rootCommand
+	}
+
+	return strings.Split(root.CommandPath(), " ")[0]
+}
+
+// shellIdentifier replaces all characters that are not allowed in shell function names
+func shellIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, filepath.Base(name))
+}
+
+// runCompleteCommand writes the completion candidates for the given words to the output,
+// the last word is the one that gets completed
+func (c *Command) runCompleteCommand(words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	candidates, file := c.complete(words[:len(words)-1], words[len(words)-1])
+
+	w := c.Output()
+	for _, candidate := range candidates {
+		fmt.Fprintln(w, candidate)
+	}
+	if file {
+		fmt.Fprintln(w, completionFile)
+	} else {
+		fmt.Fprintln(w, completionNoFile)
+	}
+
+	return nil
+}
+
+// complete returns completion candidates for toComplete after the already given words and
+// whether file names should be completed as well
+func (c *Command) complete(words []string, toComplete string) ([]string, bool) {
+	cmd := c
+	argPosition := 0
+	var valueFlag *structFlag
+
+	for _, word := range words {
+		if valueFlag != nil {
+			valueFlag = nil
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") && word != "-" && word != "--" {
+			if !strings.Contains(word, "=") {
+				if f, ok := cmd.lookupStructFlag(strings.TrimLeft(word, "-")); ok && f.takesValue() {
+					valueFlag = &f
+				}
+			}
+			continue
+		}
+
+		if argPosition == 0 {
+			if sub := cmd.findSubCommand([]string{word}, false); sub != nil {
+				cmd = sub
+				continue
+			}
+		}
+		argPosition++
+	}
+
+	// value of a flag given as separate word
+	if valueFlag != nil {
+		return cmd.completeFlagValue(*valueFlag, toComplete, "")
+	}
+
+	if strings.HasPrefix(toComplete, "-") {
+		// value of a flag given as -name=value
+		if i := strings.Index(toComplete, "="); i > 0 {
+			if f, ok := cmd.lookupStructFlag(strings.TrimLeft(toComplete[:i], "-")); ok {
+				return cmd.completeFlagValue(f, toComplete[i+1:], toComplete[:i+1])
+			}
+			return nil, false
+		}
+
+		return filterPrefix(cmd.completionFlagNames(), toComplete), false
+	}
+
+	candidates := make([]string, 0)
+	if argPosition == 0 {
+		for _, sub := range cmd.subCommands {
+			if !sub.hidden {
+				candidates = append(candidates, sub.fs.Name())
+				candidates = append(candidates, sub.aliases...)
+			}
+		}
+		if len(cmd.subCommands) > 0 && cmd.findSubCommand([]string{helpCommandName}, false) == nil {
+			candidates = append(candidates, helpCommandName)
+		}
+	}
+
+	file := false
+	for _, arg := range cmd.structArgs() {
+		if arg.position != argPosition+1 {
+			continue
+		}
+
+		candidates = append(candidates, arg.oneOf...)
+		if f, ok := cmd.argCompletions[arg.position]; ok {
+			candidates = append(candidates, f(toComplete)...)
+		}
+		file = arg.file
+	}
+
+	return filterPrefix(candidates, toComplete), file
+}
+
+// completeFlagValue returns candidates for the value of a flag, prefix is prepended to every candidate
+func (c *Command) completeFlagValue(f structFlag, toComplete string, prefix string) ([]string, bool) {
+	candidates := append([]string{}, f.oneOf...)
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		if fn, ok := cmd.flagCompletions[f.name]; ok {
+			candidates = append(candidates, fn(toComplete)...)
+			break
+		}
+	}
+
+	candidates = filterPrefix(candidates, toComplete)
+	for i := range candidates {
+		candidates[i] = prefix + candidates[i]
+	}
+
+	return candidates, f.file
+}
+
+// lookupStructFlag finds a flag of this command or a persistent flag of a parent by its name or alt name
+func (c *Command) lookupStructFlag(name string) (structFlag, bool) {
+	own, inherited := c.structFlags()
+	for _, f := range append(own, inherited...) {
+		if f.name == name || (f.alt != "" && f.alt == name) {
+			return f, true
+		}
+	}
+
+	return structFlag{}, false
+}
+
+// completionFlagNames returns all visible flag names including alt names and negated bool flags
+func (c *Command) completionFlagNames() []string {
+	names := make([]string, 0)
+	own, inherited := c.structFlags()
+	for _, f := range append(own, inherited...) {
+		if f.hidden {
+			continue
+		}
+
+		names = append(names, "-"+f.name)
+		if f.alt != "" {
+			names = append(names, "-"+f.alt)
+		}
+		if f.kind == reflect.Bool {
+			names = append(names, "-no-"+f.name)
+		}
+	}
+
+	return names
+}
+
+// takesValue reports whether the flag needs a value
+func (f structFlag) takesValue() bool {
+	return f.kind != reflect.Bool && !f.count
+}
+
+func filterPrefix(candidates []string, prefix string) []string {
+	filtered := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			filtered = append(filtered, candidate)
+		}
+	}
+
+	return filtered
+}
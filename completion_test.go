@@ -0,0 +1,85 @@
+package configstruct
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type completionRootConfig struct {
+	Format string `cli:"format" oneof:"json yaml text" persistent:"true" usage:"output format"`
+	Debug  bool   `cli:"debug" usage:"debug mode"`
+	Secret string `cli:"internal" hidden:"true"`
+}
+
+type completionCountConfig struct {
+	Number   int    `cli:"number" cliAlt:"n" usage:"number to count"`
+	Output   string `cli:"output" complete:"file" usage:"output file"`
+	Filename string `arg:"1" name:"filename" complete:"file"`
+	Mode     string `arg:"2" name:"mode" oneof:"fast slow"`
+}
+
+func newCompletionCommand() *Command {
+	countCmd := NewCommand("count", "Count numbers", &completionCountConfig{}, nil).
+		SetAliases("cnt").
+		RegisterFlagCompletion("number", func(toComplete string) []string {
+			return []string{"1", "10", "100"}
+		})
+	debugCmd := NewCommand("debug", "Debug", nil, nil).SetHidden(true)
+
+	return NewCommand("", "Test CLI", &completionRootConfig{}, nil, countCmd, debugCmd)
+}
+
+func TestCommand_Complete(t *testing.T) {
+	tests := []struct {
+		name       string
+		words      []string
+		toComplete string
+		candidates []string
+		file       bool
+	}{
+		{name: "commands", words: nil, toComplete: "", candidates: []string{"count", "cnt", "help"}},
+		{name: "command prefix", words: nil, toComplete: "co", candidates: []string{"count"}},
+		{name: "flags", words: nil, toComplete: "-", candidates: []string{"-format", "-debug", "-no-debug"}},
+		{name: "oneof values", words: []string{"-format"}, toComplete: "", candidates: []string{"json", "yaml", "text"}},
+		{name: "oneof values with =", words: nil, toComplete: "-format=y", candidates: []string{"-format=yaml"}},
+		{name: "sub-command flags", words: []string{"count"}, toComplete: "-", candidates: []string{"-number", "-n", "-output", "-format"}},
+		{name: "registered flag completion", words: []string{"cnt", "-n"}, toComplete: "1", candidates: []string{"1", "10", "100"}},
+		{name: "file flag", words: []string{"count", "-output"}, toComplete: "", candidates: []string{}, file: true},
+		{name: "file argument", words: []string{"-debug", "count", "-number", "2"}, toComplete: "", candidates: []string{}, file: true},
+		{name: "oneof argument", words: []string{"count", "numbers.txt"}, toComplete: "f", candidates: []string{"fast"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, file := newCompletionCommand().complete(tt.words, tt.toComplete)
+			assert.Equal(t, tt.candidates, candidates)
+			assert.Equal(t, tt.file, file)
+		})
+	}
+}
+
+func TestCommand_CompleteCommand(t *testing.T) {
+	var out bytes.Buffer
+
+	cmd := newCompletionCommand().SetOutput(&out)
+	err := cmd.ParseAndRun([]string{"cliName", "__complete", "count", "-format", "j"})
+	assert.NoError(t, err)
+	assert.Equal(t, "json\n:nofile\n", out.String())
+}
+
+func TestCommand_GenCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := NewCommand("", "Test CLI", nil, nil).GenCompletion(&out, shell)
+			assert.NoError(t, err)
+			assert.Contains(t, out.String(), "__complete")
+		})
+	}
+
+	err := NewCommand("", "Test CLI", nil, nil).GenCompletion(&bytes.Buffer{}, "powershell")
+	assert.Error(t, err)
+}
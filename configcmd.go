@@ -0,0 +1,110 @@
+package configstruct
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+type validateCommandConfig struct {
+	File    string `arg:"1" name:"file" required:"true" complete:"file" yaml:"-" usage:"path of the YAML config file"`
+	KeyFile string `cli:"key-file" complete:"file" yaml:"-" usage:"file with the base64 encoded key to decrypt values, defaults to env CONFIGSTRUCT_ENCRYPTION_KEY"`
+}
+
+type saveCommandConfig struct {
+	File     string `arg:"1" name:"file" required:"true" complete:"file" yaml:"-" usage:"path of the YAML config file"`
+	All      bool   `cli:"all" yaml:"-" usage:"write all values instead of only the ones that differ from the defaults"`
+	Preserve bool   `cli:"preserve" yaml:"-" usage:"update an existing file in place and keep its comments"`
+}
+
+type encryptCommandConfig struct {
+	Value   string `arg:"1" name:"value" required:"true" yaml:"-" usage:"value to encrypt, - reads it from stdin"`
+	KeyFile string `cli:"key-file" complete:"file" yaml:"-" usage:"file with the base64 encoded key, defaults to env CONFIGSTRUCT_ENCRYPTION_KEY"`
+}
+
+// NewConfigCommand creates a command named config with sub-commands to work with config files of the config struct c,
+// add it as sub-command to your root command:
+//
+//	config validate <file>    validates a config file with ValidateFile
+//	config save <file>        saves the values of c that differ from the values c had when NewConfigCommand was called
+//	config encrypt <value>    prints a value encrypted with EncryptValue to be used in config files
+func NewConfigCommand(c interface{}) *Command {
+	defaults := snapshot(c)
+
+	validateCmd := NewCommand("validate", "Validate a config file", &validateCommandConfig{}, func(cmd *Command, cfg interface{}) error {
+		validateCfg := cfg.(*validateCommandConfig)
+		file := validateCfg.File
+		if err := ValidateFile(file, c, WithEncryptionKeyFile(validateCfg.KeyFile)); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.Output(), "config file %s is valid\n", file)
+		return nil
+	})
+
+	saveCmd := NewCommand("save", "Save the current config to a file", &saveCommandConfig{}, func(cmd *Command, cfg interface{}) error {
+		saveCfg := cfg.(*saveCommandConfig)
+
+		var opts []SaveOption
+		if !saveCfg.All {
+			opts = append(opts, WithDefaults(defaults))
+		}
+		if saveCfg.Preserve {
+			opts = append(opts, WithPreserveExisting())
+		}
+
+		if err := Save(saveCfg.File, c, opts...); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.Output(), "config saved to %s\n", saveCfg.File)
+		return nil
+	})
+
+	encryptCmd := NewCommand("encrypt", "Encrypt a value for config files", &encryptCommandConfig{}, func(cmd *Command, cfg interface{}) error {
+		encryptCfg := cfg.(*encryptCommandConfig)
+
+		key, err := resolveEncryptionKey(nil, encryptCfg.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		value := encryptCfg.Value
+		if value == "-" {
+			b, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("could not read value from stdin: %w", err)
+			}
+			value = strings.TrimRight(string(b), "\r\n")
+		}
+
+		encrypted, err := EncryptValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.Output(), encrypted)
+		return nil
+	})
+
+	return NewCommand("config", "Work with config files", nil, nil, validateCmd, saveCmd, encryptCmd)
+}
+
+// snapshot returns a shallow copy of the struct c points to with default tags applied like parsing does
+func snapshot(c interface{}) interface{} {
+	value := reflect.ValueOf(c)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return c
+	}
+
+	copied := reflect.New(value.Elem().Type())
+	copied.Elem().Set(value.Elem())
+	if copied.Elem().Kind() == reflect.Struct {
+		// invalid default tags are reported when the command is parsed
+		_ = applyDefaultTags(copied.Elem())
+	}
+
+	return copied.Interface()
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -21,16 +23,15 @@ func Parse(c interface{}, opts ...Option) error {
 
 // ParseWithFlagSet can use a specific FlagSet and args slice to parse data from
 func ParseWithFlagSet(flagSet *flag.FlagSet, cliArgs []string, c interface{}, opts ...Option) error {
-	config := config{
-		precedenceEnv: false,
-	}
-	for _, opt := range opts {
-		opt(&config)
-	}
+	config := newConfig(opts...)
 
 	if c == nil {
-		flagSet.Parse(cliArgs[1:])
-		return nil
+		return parseFlags(flagSet, cliArgs[1:], config.hiddenFlags)
+	}
+
+	// default tags fill all fields without pre-set value before config file, env and cli values are applied
+	if err := applyDefaultTags(reflect.ValueOf(c).Elem()); err != nil {
+		return err
 	}
 
 	// use reflection to deep dive into our struct
@@ -45,7 +46,7 @@ func ParseWithFlagSet(flagSet *flag.FlagSet, cliArgs []string, c interface{}, op
 				// check env
 				env := field.Tag.Get("env")
 				if env != "" {
-					if val, found := os.LookupEnv(env); found {
+					if val, found := os.LookupEnv(config.envName(env)); found {
 						config.file = val
 						break
 					}
@@ -97,7 +98,7 @@ func ParseWithFlagSet(flagSet *flag.FlagSet, cliArgs []string, c interface{}, op
 				argVal := flagSet.Arg(arg - 1)
 				if required && argVal == "" {
 					flagSet.Usage()
-					return fmt.Errorf("argument %s is required", name)
+					return &UsageError{Err: fmt.Errorf("argument %s is required", name)}
 				}
 
 				if argVal != "" {
@@ -111,103 +112,26 @@ func ParseWithFlagSet(flagSet *flag.FlagSet, cliArgs []string, c interface{}, op
 
 	// parse cli flags
 	parseCli := func() error {
-		// iterate over struct fields for cli flags
-		for i := 0; i < confType.NumField(); i++ {
-			field := confType.Field(i)
-			value := valueRef.Elem().Field(i)
-			cli := field.Tag.Get("cli")
-			cliAlt := field.Tag.Get("cliAlt")
-			usage := field.Tag.Get("usage")
-			structSliceValue := &structSliceFlag{
-				target: valueRef.Elem().FieldByName(field.Name),
-			}
-
-			setFlag := func(name string) error {
-				switch field.Type.Kind() {
-				case reflect.String:
-					flagSet.StringVar(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*string), name, value.String(), usage)
-				case reflect.Bool:
-					flagSet.BoolVar(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*bool), name, value.Bool(), usage)
-				case reflect.Int:
-					flagSet.IntVar(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*int), name, int(value.Int()), usage)
-				case reflect.Float64:
-					flagSet.Float64Var(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*float64), name, value.Float(), usage)
-				case reflect.Slice:
-					if field.Type.Elem().Kind() == reflect.Struct {
-						flagSet.Var(structSliceValue, name, usage)
-						return nil
-					}
-					return fmt.Errorf("config cli type %s not implemented", field.Type.String())
-				default:
-					return fmt.Errorf("config cli type %s not implemented", field.Type.Kind())
-				}
-
-				return nil
-			}
+		err := defineFlags(flagSet, c, false, nil)
+		if err != nil {
+			return err
+		}
 
-			if cli != "" {
-				err := setFlag(cli)
-				if err != nil {
-					return err
-				}
-			}
-			if cliAlt != "" {
-				err := setFlag(cliAlt)
-				if err != nil {
-					return err
-				}
+		hidden := hiddenFlagNames(c, false)
+		for name := range config.hiddenFlags {
+			hidden[name] = true
+		}
+		if len(hidden) > 0 && isDefaultUsage(flagSet) {
+			flagSet.Usage = func() {
+				printVisibleDefaults(flagSet, hidden)
 			}
 		}
 
-		return flagSet.Parse(cliArgs[1:])
+		return parseFlags(flagSet, cliArgs[1:], hidden)
 	}
 
 	parseEnv := func() error {
-		// iterate over struct fields for env values
-		for i := 0; i < confType.NumField(); i++ {
-			field := confType.Field(i)
-			env := field.Tag.Get("env")
-			if env == "" {
-				continue
-			}
-
-			envValue, found := os.LookupEnv(env)
-			if found {
-				switch field.Type.Kind() {
-				case reflect.String:
-					valueRef.Elem().FieldByName(field.Name).SetString(envValue)
-				case reflect.Bool:
-					valueRef.Elem().FieldByName(field.Name).SetBool(false)
-					if strings.EqualFold(envValue, "true") {
-						valueRef.Elem().FieldByName(field.Name).SetBool(true)
-					}
-				case reflect.Int:
-					value, err := strconv.ParseInt(envValue, 0, 64)
-					if err == nil {
-						valueRef.Elem().FieldByName(field.Name).SetInt(value)
-					}
-				case reflect.Float64:
-					value, err := strconv.ParseFloat(envValue, 64)
-					if err == nil {
-						valueRef.Elem().FieldByName(field.Name).SetFloat(value)
-					}
-				case reflect.Slice:
-					if field.Type.Elem().Kind() != reflect.Struct {
-						return fmt.Errorf("config env type %s not implemented", field.Type.String())
-					}
-
-					sliceValue, err := decodeStructSliceJSON(envValue, field.Type)
-					if err != nil {
-						return fmt.Errorf("could not parse env %s for field %s: %w", env, field.Name, err)
-					}
-					valueRef.Elem().FieldByName(field.Name).Set(sliceValue)
-				default:
-					return fmt.Errorf("config env type %s not implemented", field.Type.String())
-				}
-			}
-		}
-
-		return nil
+		return applyEnv(c, config)
 	}
 
 	var err error
@@ -244,6 +168,272 @@ func ParseWithFlagSet(flagSet *flag.FlagSet, cliArgs []string, c interface{}, op
 	return nil
 }
 
+// applyEnv sets all fields of the struct c that have an env tag to the values of the env variables if present
+func applyEnv(c interface{}, config config) error {
+	valueRef := reflect.ValueOf(c)
+	confType := valueRef.Elem().Type()
+
+	// iterate over struct fields for env values
+	for i := 0; i < confType.NumField(); i++ {
+		field := confType.Field(i)
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		envValue, found := os.LookupEnv(config.envName(env))
+		if found {
+			switch field.Type.Kind() {
+			case reflect.String:
+				valueRef.Elem().FieldByName(field.Name).SetString(envValue)
+			case reflect.Bool:
+				valueRef.Elem().FieldByName(field.Name).SetBool(false)
+				if strings.EqualFold(envValue, "true") {
+					valueRef.Elem().FieldByName(field.Name).SetBool(true)
+				}
+			case reflect.Int:
+				value, err := strconv.ParseInt(envValue, 0, 64)
+				if err == nil {
+					valueRef.Elem().FieldByName(field.Name).SetInt(value)
+				}
+			case reflect.Float64:
+				value, err := strconv.ParseFloat(envValue, 64)
+				if err == nil {
+					valueRef.Elem().FieldByName(field.Name).SetFloat(value)
+				}
+			case reflect.Slice:
+				if field.Type.Elem().Kind() != reflect.Struct {
+					return fmt.Errorf("config env type %s not implemented", field.Type.String())
+				}
+
+				sliceValue, err := decodeStructSliceJSON(envValue, field.Type)
+				if err != nil {
+					return fmt.Errorf("could not parse env %s for field %s: %w", config.envName(env), field.Name, err)
+				}
+				valueRef.Elem().FieldByName(field.Name).Set(sliceValue)
+			default:
+				return fmt.Errorf("config env type %s not implemented", field.Type.String())
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyDefaultTags sets every zero field with a default tag of a struct value, nested structs are handled as well
+func applyDefaultTags(value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldValue := value.Field(i)
+		if !fieldValue.CanSet() {
+			continue
+		}
+
+		if tag, ok := field.Tag.Lookup("default"); ok {
+			if !fieldValue.IsZero() {
+				continue
+			}
+			if err := decodeTagValue(tag, fieldValue); err != nil {
+				return fmt.Errorf("invalid default value for field %s: %w", field.Name, err)
+			}
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Struct {
+			if err := applyDefaultTags(fieldValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseFlags parses args with the FlagSet, errors are returned as *UsageError
+func parseFlags(flagSet *flag.FlagSet, args []string, hidden map[string]bool) error {
+	args = expandCountFlags(flagSet, args)
+
+	if name := undefinedFlag(flagSet, args); name != "" {
+		names := make([]string, 0)
+		flagSet.VisitAll(func(f *flag.Flag) {
+			if !hidden[f.Name] {
+				names = append(names, f.Name)
+			}
+		})
+
+		// same output as the flag package would write
+		fmt.Fprintf(flagSet.Output(), "flag provided but not defined: -%s\n", name)
+		if flagSet.Usage != nil {
+			flagSet.Usage()
+		} else {
+			flagSet.PrintDefaults()
+		}
+
+		return &UsageError{Err: &UnknownFlagError{Name: name, Suggestions: suggest(name, names)}}
+	}
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+
+	return nil
+}
+
+// defaultUsage is the code pointer of the usage func the flag package sets for new FlagSets
+var defaultUsage = reflect.ValueOf(flag.NewFlagSet("", flag.ContinueOnError).Usage).Pointer()
+
+// isDefaultUsage reports whether the FlagSet has no usage func or the default one of the flag package,
+// a usage func set by the caller is never replaced
+func isDefaultUsage(flagSet *flag.FlagSet) bool {
+	return flagSet.Usage == nil || reflect.ValueOf(flagSet.Usage).Pointer() == defaultUsage
+}
+
+// printVisibleDefaults writes the same usage as the flag package would but without the hidden flags
+func printVisibleDefaults(flagSet *flag.FlagSet, hidden map[string]bool) {
+	if flagSet.Name() == "" {
+		fmt.Fprintf(flagSet.Output(), "Usage:\n")
+	} else {
+		fmt.Fprintf(flagSet.Output(), "Usage of %s:\n", flagSet.Name())
+	}
+
+	visible := flag.NewFlagSet(flagSet.Name(), flag.ContinueOnError)
+	visible.SetOutput(flagSet.Output())
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if hidden[f.Name] {
+			return
+		}
+		visible.Var(f.Value, f.Name, f.Usage)
+		visible.Lookup(f.Name).DefValue = f.DefValue
+	})
+	visible.PrintDefaults()
+}
+
+// undefinedFlag returns the name of the first flag in args that is not defined on the FlagSet or an empty string,
+// args are scanned the same way the flag package does until the first non-flag argument
+func undefinedFlag(flagSet *flag.FlagSet, args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return ""
+		}
+
+		name := strings.TrimPrefix(arg[1:], "-")
+		if name == "" || name[0] == '-' || name[0] == '=' {
+			// invalid syntax is reported by the flag package
+			return ""
+		}
+
+		hasValue := false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name = name[:idx]
+			hasValue = true
+		}
+
+		f := flagSet.Lookup(name)
+		if f == nil {
+			if name == "h" || name == "help" {
+				return ""
+			}
+			return name
+		}
+
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); (!ok || !boolFlag.IsBoolFlag()) && !hasValue {
+			// the next argument is the value of this flag
+			i++
+		}
+	}
+
+	return ""
+}
+
+// defineFlags defines cli flags on the FlagSet for all fields of the struct c that have a cli or cliAlt tag,
+// if persistentOnly is set only fields tagged with persistent:"true" that are not yet defined and not in
+// shadowed are used
+func defineFlags(flagSet *flag.FlagSet, c interface{}, persistentOnly bool, shadowed map[string]bool) error {
+	valueRef := reflect.ValueOf(c)
+	confType := valueRef.Elem().Type()
+
+	// iterate over struct fields for cli flags
+	for i := 0; i < confType.NumField(); i++ {
+		field := confType.Field(i)
+		value := valueRef.Elem().Field(i)
+		cli := field.Tag.Get("cli")
+		cliAlt := field.Tag.Get("cliAlt")
+		usage := field.Tag.Get("usage")
+		structSliceValue := &structSliceFlag{
+			target: valueRef.Elem().FieldByName(field.Name),
+		}
+
+		if persistentOnly && field.Tag.Get("persistent") != "true" {
+			continue
+		}
+
+		var counter *countFlag
+		setFlag := func(name string) error {
+			if persistentOnly && (flagSet.Lookup(name) != nil || shadowed[name]) {
+				return nil
+			}
+
+			switch field.Type.Kind() {
+			case reflect.String:
+				flagSet.StringVar(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*string), name, value.String(), usage)
+			case reflect.Bool:
+				target := valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*bool)
+				flagSet.BoolVar(target, name, value.Bool(), usage)
+				// every bool flag gets a negated counterpart, e.g. -no-debug for -debug
+				if name == cli && flagSet.Lookup("no-"+name) == nil && !shadowed["no-"+name] {
+					flagSet.Var(&negatedBoolFlag{target: target}, "no-"+name, "disable "+name)
+				}
+			case reflect.Int:
+				target := valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*int)
+				if field.Tag.Get("count") == "true" {
+					// cli and cliAlt share one counter, e.g. -v -verbose is 2
+					if counter == nil {
+						counter = &countFlag{target: target}
+					}
+					flagSet.Var(counter, name, usage)
+					return nil
+				}
+				flagSet.IntVar(target, name, int(value.Int()), usage)
+			case reflect.Float64:
+				flagSet.Float64Var(valueRef.Elem().FieldByName(field.Name).Addr().Interface().(*float64), name, value.Float(), usage)
+			case reflect.Slice:
+				if field.Type.Elem().Kind() == reflect.Struct {
+					flagSet.Var(structSliceValue, name, usage)
+					return nil
+				}
+				return fmt.Errorf("config cli type %s not implemented", field.Type.String())
+			default:
+				return fmt.Errorf("config cli type %s not implemented", field.Type.Kind())
+			}
+
+			return nil
+		}
+
+		for _, name := range []string{cli, cliAlt} {
+			if name == "" {
+				continue
+			}
+
+			err := setFlag(name)
+			if err != nil {
+				return err
+			}
+
+			// secret values are never printed as default in usage output
+			if isSecretField(field) && !value.IsZero() {
+				if fl := flagSet.Lookup(name); fl != nil {
+					fl.DefValue = secretMask
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 type structSliceFlag struct {
 	target reflect.Value
 	seen   bool
@@ -281,6 +471,105 @@ func (f *structSliceFlag) Set(value string) error {
 	return nil
 }
 
+// negatedBoolFlag sets the target bool to the opposite of the given flag value
+type negatedBoolFlag struct {
+	target *bool
+}
+
+func (f *negatedBoolFlag) String() string {
+	return "false"
+}
+
+func (f *negatedBoolFlag) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*f.target = !v
+	return nil
+}
+
+func (f *negatedBoolFlag) IsBoolFlag() bool {
+	return true
+}
+
+// countFlag increments the target int every time the flag is given, an explicit value sets it directly,
+// values from env or config file are replaced on the first occurrence
+type countFlag struct {
+	target *int
+	seen   bool
+}
+
+func (f *countFlag) String() string {
+	if f.target == nil {
+		return "0"
+	}
+
+	return strconv.Itoa(*f.target)
+}
+
+func (f *countFlag) Set(value string) error {
+	if !f.seen {
+		*f.target = 0
+		f.seen = true
+	}
+
+	switch value {
+	case "true":
+		*f.target++
+		return nil
+	case "false":
+		*f.target = 0
+		return nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*f.target = v
+	return nil
+}
+
+func (f *countFlag) IsBoolFlag() bool {
+	return true
+}
+
+// expandCountFlags rewrites grouped single letter count flags like -vvv to -v -v -v
+func expandCountFlags(flagSet *flag.FlagSet, args []string) []string {
+	countFlags := make(map[string]bool)
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if _, ok := f.Value.(*countFlag); ok && len(f.Name) == 1 {
+			countFlags[f.Name] = true
+		}
+	})
+	if len(countFlags) == 0 {
+		return args
+	}
+
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...)
+		}
+
+		name := strings.TrimPrefix(arg, "-")
+		if len(name) > 1 && !strings.HasPrefix(name, "-") && flagSet.Lookup(name) == nil && countFlags[name[:1]] &&
+			strings.Count(name, name[:1]) == len(name) {
+			for range name {
+				expanded = append(expanded, "-"+name[:1])
+			}
+			continue
+		}
+
+		expanded = append(expanded, arg)
+	}
+
+	return expanded
+}
+
 func decodeStructSliceJSON(value string, fieldType reflect.Type) (reflect.Value, error) {
 	if fieldType.Kind() != reflect.Slice || fieldType.Elem().Kind() != reflect.Struct {
 		return reflect.Value{}, fmt.Errorf("type %s is not a slice of structs", fieldType.String())
@@ -303,8 +592,29 @@ func decodeStructSliceJSON(value string, fieldType reflect.Type) (reflect.Value,
 
 type structFlag struct {
 	name         string
+	alt          string
 	description  string
 	defaultValue interface{}
+	kind         reflect.Kind
+	env          string
+	yamlKey      string
+	group        string
+	required     bool
+	hidden       bool
+	persistent   bool
+	count        bool
+	secret       bool
+	oneOf        []string
+	file         bool
+}
+
+type structArg struct {
+	position    int
+	name        string
+	description string
+	required    bool
+	oneOf       []string
+	file        bool
 }
 
 func getStructFlags(c interface{}) []structFlag {
@@ -318,25 +628,151 @@ func getStructFlags(c interface{}) []structFlag {
 		value := valueRef.Elem().Field(i)
 		cli := field.Tag.Get("cli")
 		usage := field.Tag.Get("usage")
+		if cli == "" {
+			continue
+		}
 
 		f = append(f, structFlag{
 			name:         cli,
+			alt:          field.Tag.Get("cliAlt"),
 			description:  usage,
-			defaultValue: value,
+			defaultValue: value.Interface(),
+			kind:         field.Type.Kind(),
+			env:          field.Tag.Get("env"),
+			yamlKey:      yamlKey(field),
+			group:        field.Tag.Get("group"),
+			required:     field.Tag.Get("required") == "true",
+			hidden:       field.Tag.Get("hidden") == "true",
+			persistent:   field.Tag.Get("persistent") == "true",
+			count:        field.Tag.Get("count") == "true",
+			secret:       isSecretField(field),
+			oneOf:        strings.Fields(field.Tag.Get("oneof")),
+			file:         field.Tag.Get("complete") == "file" || field.Tag.Get("config") == "true",
 		})
 	}
 
 	return f
 }
 
+// structFlagNames returns the names of all flags of the struct c including negated bool flags
+func structFlagNames(c interface{}) map[string]bool {
+	names := make(map[string]bool)
+	if c == nil {
+		return names
+	}
+
+	for _, f := range getStructFlags(c) {
+		names[f.name] = true
+		if f.alt != "" {
+			names[f.alt] = true
+		}
+		if f.kind == reflect.Bool {
+			names["no-"+f.name] = true
+		}
+	}
+
+	return names
+}
+
+// hiddenFlagNames returns the names of all hidden flags of a config struct including alternative and negated names
+func hiddenFlagNames(c interface{}, persistentOnly bool) map[string]bool {
+	names := make(map[string]bool)
+	if c == nil {
+		return names
+	}
+
+	for _, f := range getStructFlags(c) {
+		if !f.hidden || (persistentOnly && !f.persistent) {
+			continue
+		}
+		names[f.name] = true
+		if f.alt != "" {
+			names[f.alt] = true
+		}
+		if f.kind == reflect.Bool {
+			names["no-"+f.name] = true
+		}
+	}
+
+	return names
+}
+
+func getStructArgs(c interface{}) []structArg {
+	a := make([]structArg, 0)
+
+	confType := reflect.TypeOf(c).Elem()
+	for i := 0; i < confType.NumField(); i++ {
+		field := confType.Field(i)
+		position, err := strconv.Atoi(field.Tag.Get("arg"))
+		if err != nil || position <= 0 {
+			continue
+		}
+
+		name := field.Tag.Get("name")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		a = append(a, structArg{
+			position:    position,
+			name:        name,
+			description: field.Tag.Get("usage"),
+			required:    field.Tag.Get("required") == "true",
+			oneOf:       strings.Fields(field.Tag.Get("oneof")),
+			file:        field.Tag.Get("complete") == "file",
+		})
+	}
+
+	sort.Slice(a, func(i, j int) bool {
+		return a[i].position < a[j].position
+	})
+
+	return a
+}
+
+// yamlKey returns the key of a field in a YAML config file, it is empty if the field is skipped
+func yamlKey(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag == "-" || field.PkgPath != "" {
+		return ""
+	}
+	if tag == "" {
+		return strings.ToLower(field.Name)
+	}
+
+	return tag
+}
+
 func readConfigFile(c interface{}, cfg config) error {
-	f, err := os.Open(cfg.file)
+	b, err := os.ReadFile(cfg.file)
 	if err != nil {
 		return fmt.Errorf("could not open config file %s: %w", cfg.file, err)
 	}
-	defer f.Close()
 
-	err = yaml.NewDecoder(f).Decode(c)
+	var doc yaml.Node
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return fmt.Errorf("could not decode yaml config file %s: %w", cfg.file, err)
+	}
+	if doc.Kind == 0 {
+		return fmt.Errorf("could not decode yaml config file %s: %w", cfg.file, io.EOF)
+	}
+
+	if encryptionKeySet(cfg.encryptionKey, cfg.encryptionKeyFile) {
+		err = decryptNodes(&doc, cfg.encryptionKey, cfg.encryptionKeyFile)
+		if err != nil {
+			return fmt.Errorf("could not decrypt config file %s: %w", cfg.file, err)
+		}
+	}
+
+	if cfg.strictConfig {
+		issues := validator{unknownKeysOnly: true}.validateNode(&doc, knownConfigType(c, cfg.sharedConfigs), "")
+		if len(issues) > 0 {
+			return &ValidationError{File: cfg.file, Issues: issues}
+		}
+	}
+
+	err = doc.Decode(c)
 	if err != nil {
 		return fmt.Errorf("could not decode yaml config file %s: %w", cfg.file, err)
 	}
@@ -344,21 +780,31 @@ func readConfigFile(c interface{}, cfg config) error {
 	return nil
 }
 
-// Save writes the given config struct as YAML to a file
-func Save(path string, c interface{}) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("could not create config file %s: %w", path, err)
+// knownConfigType returns the type of c or, if the config file is shared by several config structs,
+// a struct type that inlines all of them so the keys of every struct are known
+func knownConfigType(c interface{}, shared []interface{}) reflect.Type {
+	if len(shared) == 0 {
+		return reflect.TypeOf(c)
 	}
-	defer f.Close()
 
-	encoder := yaml.NewEncoder(f)
-	defer encoder.Close()
+	fields := make([]reflect.StructField, 0, len(shared)+1)
+	seen := make(map[reflect.Type]bool)
+	for _, config := range append([]interface{}{c}, shared...) {
+		t := reflect.TypeOf(config)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			continue
+		}
+		seen[t] = true
 
-	err = encoder.Encode(c)
-	if err != nil {
-		return fmt.Errorf("could not encode yaml config %s: %w", path, err)
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("Config%d", len(fields)),
+			Type: t,
+			Tag:  `yaml:",inline"`,
+		})
 	}
 
-	return nil
+	return reflect.StructOf(fields)
 }
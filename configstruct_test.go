@@ -1,10 +1,12 @@
 package configstruct
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -96,6 +98,23 @@ func TestParse(t *testing.T) {
 		assert.True(t, conf.Debug)
 	})
 
+	t.Run("hidden flags are not in usage", func(t *testing.T) {
+		type hiddenConfig struct {
+			Hostname string `cli:"hostname" usage:"hostname value"`
+			Debug    bool   `cli:"debug" hidden:"true" usage:"internal debug mode"`
+		}
+
+		var out bytes.Buffer
+		flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+		flagSet.SetOutput(&out)
+
+		err := ParseWithFlagSet(flagSet, []string{"command", "-h"}, &hiddenConfig{})
+		assert.ErrorIs(t, err, flag.ErrHelp)
+		assert.Contains(t, out.String(), "Usage of command:")
+		assert.Contains(t, out.String(), "hostname value")
+		assert.NotContains(t, out.String(), "debug")
+	})
+
 	t.Run("cli with defaults", func(t *testing.T) {
 		os.Args = []string{"command", "-hostname", "myhost"}
 		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -118,6 +137,90 @@ func TestParse(t *testing.T) {
 		assert.Equal(t, 300.1, conf.FloatValue)
 	})
 
+	t.Run("negated bool flag", func(t *testing.T) {
+		cliArgs := []string{"command", "-no-debug"}
+		flagSet := flag.NewFlagSet(cliArgs[0], flag.ContinueOnError)
+
+		conf := testConfig{Debug: true}
+
+		err := ParseWithFlagSet(flagSet, cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.False(t, conf.Debug)
+	})
+
+	t.Run("count flag", func(t *testing.T) {
+		type Config struct {
+			Verbose int  `cli:"verbose" cliAlt:"v" count:"true" usage:"verbosity level"`
+			Debug   bool `cli:"d" usage:"debug mode"`
+		}
+
+		cliArgs := []string{"command", "-vvv", "-verbose", "-d"}
+		flagSet := flag.NewFlagSet(cliArgs[0], flag.ContinueOnError)
+
+		conf := Config{}
+
+		err := ParseWithFlagSet(flagSet, cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, conf.Verbose)
+		assert.True(t, conf.Debug)
+
+		conf = Config{}
+		cliArgs = []string{"command", "-verbose=2"}
+		err = ParseWithFlagSet(flag.NewFlagSet(cliArgs[0], flag.ContinueOnError), cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, conf.Verbose)
+
+		conf = Config{}
+		cliArgs = []string{"command", "-v", "-v=false"}
+		err = ParseWithFlagSet(flag.NewFlagSet(cliArgs[0], flag.ContinueOnError), cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, conf.Verbose)
+	})
+
+	t.Run("default tags", func(t *testing.T) {
+		type Config struct {
+			Hostname string        `env:"CONFIGSTRUCT_DEFAULT_HOSTNAME" cli:"hostname" default:"localhost"`
+			Port     int           `cli:"port" default:"8080"`
+			Timeout  time.Duration `default:"5s"`
+			Preset   string        `cli:"preset" default:"tag"`
+			Log      struct {
+				Level string `yaml:"level" default:"info"`
+			}
+		}
+
+		os.Setenv("CONFIGSTRUCT_DEFAULT_HOSTNAME", "envhost")
+		defer os.Unsetenv("CONFIGSTRUCT_DEFAULT_HOSTNAME")
+
+		cliArgs := []string{"command", "-port=9000"}
+		flagSet := flag.NewFlagSet(cliArgs[0], flag.ContinueOnError)
+		conf := Config{Preset: "value"}
+
+		err := ParseWithFlagSet(flagSet, cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, "envhost", conf.Hostname)
+		assert.Equal(t, 9000, conf.Port)
+		assert.Equal(t, 5*time.Second, conf.Timeout)
+		assert.Equal(t, "value", conf.Preset)
+		assert.Equal(t, "info", conf.Log.Level)
+		assert.Equal(t, "8080", flagSet.Lookup("port").DefValue)
+	})
+
+	t.Run("count flag overrides env", func(t *testing.T) {
+		type Config struct {
+			Verbose int `env:"CONFIGSTRUCT_VERBOSE" cli:"v" count:"true" usage:"verbosity level"`
+		}
+
+		os.Setenv("CONFIGSTRUCT_VERBOSE", "2")
+		defer os.Unsetenv("CONFIGSTRUCT_VERBOSE")
+
+		cliArgs := []string{"command", "-v"}
+		conf := Config{}
+
+		err := ParseWithFlagSet(flag.NewFlagSet(cliArgs[0], flag.ContinueOnError), cliArgs, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, conf.Verbose)
+	})
+
 	t.Run("not implemented types", func(t *testing.T) {
 		cliArgs := []string{"command", "-hostname=localhost", "-port=8080", "-debug=true", "-floatValue=100.5"}
 		flagSet := flag.NewFlagSet(cliArgs[0], flag.ExitOnError)
@@ -217,6 +320,29 @@ func TestParse(t *testing.T) {
 		assert.Equal(t, conf.Port, conf2.Port)
 	})
 
+	t.Run("strict yaml rejects unknown keys", func(t *testing.T) {
+		type Config struct {
+			Hostname string `yaml:"hostname" cli:"hostname"`
+			Port     int    `yaml:"port" cli:"port"`
+		}
+
+		tmpFile := "test_strict.yaml"
+		defer os.Remove(tmpFile)
+
+		err := os.WriteFile(tmpFile, []byte("hostnmae: test\nport: 1234\n"), 0600)
+		assert.NoError(t, err)
+
+		conf := Config{}
+		err = ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, WithYamlConfig(tmpFile))
+		assert.NoError(t, err)
+		assert.Equal(t, 1234, conf.Port)
+
+		conf = Config{}
+		err = ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, WithYamlConfig(tmpFile), WithStrictConfig())
+		assert.EqualError(t, err, "config file test_strict.yaml is invalid:\n  test_strict.yaml:1:1: hostnmae: unknown key, did you mean hostname?")
+		assert.Empty(t, conf.Hostname)
+	})
+
 	t.Run("dynamic config path via cli", func(t *testing.T) {
 		type Config struct {
 			ConfigPath string `cli:"config" config:"true"`
@@ -0,0 +1,272 @@
+package configstruct
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GenManTree writes a roff man page for this command and all visible sub-commands to dir,
+// the files are named by their command path like mycmd-count.1
+func (c *Command) GenManTree(dir string) error {
+	return c.genTree(dir, "-", ".1", (*Command).GenMan)
+}
+
+// GenMarkdownTree writes a Markdown reference page for this command and all visible sub-commands to dir,
+// the files are named by their command path like mycmd_count.md
+func (c *Command) GenMarkdownTree(dir string) error {
+	return c.genTree(dir, "_", ".md", (*Command).GenMarkdown)
+}
+
+func (c *Command) genTree(dir string, separator string, ext string, gen func(c *Command, w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := gen(c, &buf); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, c.docName(separator)+ext)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write documentation file %s: %w", path, err)
+	}
+
+	for _, sub := range c.subCommands {
+		if sub.hidden {
+			continue
+		}
+		if err := sub.genTree(dir, separator, ext, gen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// docName returns the command path joined by separator
+func (c *Command) docName(separator string) string {
+	return strings.Replace(c.CommandPath(), " ", separator, -1)
+}
+
+// GenMan writes a roff man page for this command to w
+func (c *Command) GenMan(w io.Writer) error {
+	data := c.helpData()
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, ".TH \"%s\" \"1\"\n", strings.ToUpper(c.docName("-")))
+	fmt.Fprintf(buf, ".SH NAME\n%s \\- %s\n", manEscape(c.docName("-")), manEscape(data.Description))
+	fmt.Fprintf(buf, ".SH SYNOPSIS\n.B %s\n", manEscape(data.UsageLine))
+
+	description := data.LongDescription
+	if description == "" {
+		description = data.Description
+	}
+	if description != "" {
+		fmt.Fprintf(buf, ".SH DESCRIPTION\n%s\n", manEscape(description))
+	}
+
+	if len(data.Aliases) > 0 {
+		fmt.Fprintf(buf, ".SH ALIASES\n%s\n", manEscape(strings.Join(data.Aliases, ", ")))
+	}
+
+	if len(data.FlagGroups) > 0 {
+		fmt.Fprintf(buf, ".SH OPTIONS\n")
+	}
+	for _, group := range data.FlagGroups {
+		if group.Title != "Flags" {
+			fmt.Fprintf(buf, ".SS %s\n", manEscape(group.Title))
+		}
+		for _, f := range group.Flags {
+			fmt.Fprintf(buf, ".TP\n\\fB%s\\fR", manEscape("-"+f.Name))
+			if f.IsBool {
+				fmt.Fprintf(buf, ", \\fB%s\\fR", manEscape("-no-"+f.Name))
+			}
+			if f.Alt != "" {
+				fmt.Fprintf(buf, ", \\fB%s\\fR", manEscape("-"+f.Alt))
+			}
+			if f.Type != "" {
+				fmt.Fprintf(buf, " \\fI%s\\fR", f.Type)
+			}
+			fmt.Fprintln(buf)
+			if details := f.Details(); details != "" {
+				fmt.Fprintf(buf, "%s\n", manEscape(details))
+			}
+			if f.Env != "" {
+				fmt.Fprintf(buf, ".br\nEnvironment: \\fB%s\\fR\n", manEscape(f.Env))
+			}
+			if f.YamlKey != "" {
+				fmt.Fprintf(buf, ".br\nConfig key: \\fB%s\\fR\n", manEscape(f.YamlKey))
+			}
+		}
+	}
+
+	if len(data.Args) > 0 {
+		fmt.Fprintf(buf, ".SH ARGUMENTS\n")
+		for _, arg := range data.Args {
+			fmt.Fprintf(buf, ".TP\n\\fB%s\\fR\n", manEscape(arg.Name))
+			if details := arg.Details(); details != "" {
+				fmt.Fprintf(buf, "%s\n", manEscape(details))
+			}
+		}
+	}
+
+	if commands := c.docSubCommands(); len(commands) > 0 {
+		fmt.Fprintf(buf, ".SH COMMANDS\n")
+		for _, sub := range commands {
+			fmt.Fprintf(buf, ".TP\n\\fB%s\\fR\n%s\n", manEscape(sub.fs.Name()), manEscape(sub.description))
+		}
+	}
+
+	if len(data.Examples) > 0 {
+		fmt.Fprintf(buf, ".SH EXAMPLES\n.nf\n")
+		for _, example := range data.Examples {
+			fmt.Fprintf(buf, "%s\n", manEscape(strings.TrimRight(example, "\n")))
+		}
+		fmt.Fprintf(buf, ".fi\n")
+	}
+
+	if related := c.docRelated(); len(related) > 0 {
+		refs := make([]string, 0, len(related))
+		for _, cmd := range related {
+			refs = append(refs, fmt.Sprintf("\\fB%s\\fR(1)", manEscape(cmd.docName("-"))))
+		}
+		fmt.Fprintf(buf, ".SH SEE ALSO\n%s\n", strings.Join(refs, ", "))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// GenMarkdown writes a Markdown reference page for this command to w
+func (c *Command) GenMarkdown(w io.Writer) error {
+	data := c.helpData()
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "## %s\n\n", data.Path)
+	if data.Description != "" {
+		fmt.Fprintf(buf, "%s\n\n", data.Description)
+	}
+	if data.LongDescription != "" {
+		fmt.Fprintf(buf, "%s\n\n", data.LongDescription)
+	}
+
+	fmt.Fprintf(buf, "### Synopsis\n\n```\n%s\n```\n\n", data.UsageLine)
+
+	if len(data.Aliases) > 0 {
+		fmt.Fprintf(buf, "### Aliases\n\n`%s`\n\n", strings.Join(data.Aliases, "`, `"))
+	}
+
+	for _, group := range data.FlagGroups {
+		fmt.Fprintf(buf, "### %s\n\n", group.Title)
+		fmt.Fprintf(buf, "| Flag | Alias | Type | Description | Default | Env | YAML key | Required |\n")
+		fmt.Fprintf(buf, "|------|-------|------|-------------|---------|-----|----------|----------|\n")
+		for _, f := range group.Flags {
+			name := "`-" + f.Name + "`"
+			if f.IsBool {
+				name += ", `-no-" + f.Name + "`"
+			}
+			fmt.Fprintf(buf, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+				name, markdownCode("-", f.Alt), f.Type, markdownEscape(f.Usage), markdownCode("", f.Default),
+				markdownCode("", f.Env), markdownCode("", f.YamlKey), yesNo(f.Required))
+		}
+		fmt.Fprintln(buf)
+	}
+
+	if len(data.Args) > 0 {
+		fmt.Fprintf(buf, "### Arguments\n\n")
+		fmt.Fprintf(buf, "| Position | Name | Description | Required |\n")
+		fmt.Fprintf(buf, "|----------|------|-------------|----------|\n")
+		for _, arg := range data.Args {
+			fmt.Fprintf(buf, "| %d | `%s` | %s | %s |\n", arg.Position, arg.Name, markdownEscape(arg.Usage), yesNo(arg.Required))
+		}
+		fmt.Fprintln(buf)
+	}
+
+	if commands := c.docSubCommands(); len(commands) > 0 {
+		fmt.Fprintf(buf, "### Commands\n\n")
+		for _, sub := range commands {
+			fmt.Fprintf(buf, "* [%s](%s.md) - %s\n", sub.CommandPath(), sub.docName("_"), sub.description)
+		}
+		fmt.Fprintln(buf)
+	}
+
+	if len(data.Examples) > 0 {
+		fmt.Fprintf(buf, "### Examples\n\n```\n")
+		for _, example := range data.Examples {
+			fmt.Fprintf(buf, "%s\n", strings.TrimRight(example, "\n"))
+		}
+		fmt.Fprintf(buf, "```\n\n")
+	}
+
+	if c.rootCommand != nil {
+		fmt.Fprintf(buf, "### See also\n\n")
+		fmt.Fprintf(buf, "* [%s](%s.md) - %s\n", c.rootCommand.CommandPath(), c.rootCommand.docName("_"), c.rootCommand.description)
+	}
+
+	_, err := w.Write(bytes.TrimRight(buf.Bytes(), "\n"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// docSubCommands returns all visible sub-commands
+func (c *Command) docSubCommands() []*Command {
+	commands := make([]*Command, 0, len(c.subCommands))
+	for _, sub := range c.subCommands {
+		if !sub.hidden {
+			commands = append(commands, sub)
+		}
+	}
+
+	return commands
+}
+
+// docRelated returns the parent and all visible sub-commands
+func (c *Command) docRelated() []*Command {
+	related := make([]*Command, 0)
+	if c.rootCommand != nil {
+		related = append(related, c.rootCommand)
+	}
+
+	return append(related, c.docSubCommands()...)
+}
+
+// manEscape escapes text for roff
+func manEscape(text string) string {
+	text = strings.Replace(text, `\`, `\e`, -1)
+	text = strings.Replace(text, "-", `\-`, -1)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownEscape escapes text for the use in Markdown tables
+func markdownEscape(text string) string {
+	return strings.Replace(strings.Replace(text, "|", `\|`, -1), "\n", " ", -1)
+}
+
+// markdownCode formats a value as inline code with the given prefix, empty values stay empty
+func markdownCode(prefix string, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return "`" + prefix + markdownEscape(value) + "`"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
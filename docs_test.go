@@ -0,0 +1,74 @@
+package configstruct
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDocsCommand() *Command {
+	countCmd := NewCommand("count", "Count numbers", &helpCountConfig{}, nil).
+		SetAliases("cnt").
+		SetExamples("mycmd count -number=2 numbers.txt")
+	debugCmd := NewCommand("debug", "Debug internals", nil, nil).SetHidden(true)
+
+	return NewCommand("mycmd", "Test CLI", &helpRootConfig{Hostname: "localhost"}, nil, countCmd, debugCmd)
+}
+
+func TestCommand_GenMarkdown(t *testing.T) {
+	var out bytes.Buffer
+
+	cmd := newDocsCommand()
+	err := cmd.subCommands[0].GenMarkdown(&out)
+	assert.NoError(t, err)
+
+	md := out.String()
+	assert.Contains(t, md, "## mycmd count\n\nCount numbers\n")
+	assert.Contains(t, md, "```\nmycmd count [flags] <filename>\n```")
+	assert.Contains(t, md, "### Aliases\n\n`count`, `cnt`\n")
+	assert.Contains(t, md, "| `-number` |  | int | number to count |  |  | `number` | no |\n")
+	assert.Contains(t, md, "| `-verbose` | `-v` |  | verbosity level |  |  | `verbose` | no |\n")
+	assert.Contains(t, md, "### Global Flags\n")
+	assert.Contains(t, md, "| `-hostname` |  | string | hostname value | `\"localhost\"` | `CONFIGSTRUCT_HOSTNAME` | `host` | no |\n")
+	assert.Contains(t, md, "| 1 | `filename` | file to count | yes |\n")
+	assert.Contains(t, md, "* [mycmd](mycmd.md) - Test CLI\n")
+}
+
+func TestCommand_GenMan(t *testing.T) {
+	var out bytes.Buffer
+
+	cmd := newDocsCommand()
+	err := cmd.GenMan(&out)
+	assert.NoError(t, err)
+
+	man := out.String()
+	out.Reset()
+	assert.NoError(t, cmd.subCommands[0].GenMan(&out))
+	assert.Contains(t, out.String(), ".TP\n\\fB\\-verbose\\fR, \\fB\\-v\\fR\nverbosity level\n")
+	assert.Contains(t, man, ".TH \"MYCMD\" \"1\"\n")
+	assert.Contains(t, man, ".SH NAME\nmycmd \\- Test CLI\n")
+	assert.Contains(t, man, ".TP\n\\fB\\-hostname\\fR \\fIstring\\fR\nhostname value (default \"localhost\")\n.br\nEnvironment: \\fBCONFIGSTRUCT_HOSTNAME\\fR\n")
+	assert.Contains(t, man, ".SS Network Flags\n.TP\n\\fB\\-port\\fR, \\fB\\-p\\fR \\fIint\\fR\n")
+	assert.Contains(t, man, ".SH COMMANDS\n.TP\n\\fBcount\\fR\nCount numbers\n")
+	assert.NotContains(t, man, "debug")
+	assert.Contains(t, man, ".SH SEE ALSO\n\\fBmycmd\\-count\\fR(1)\n")
+}
+
+func TestCommand_GenTree(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := newDocsCommand()
+	assert.NoError(t, cmd.GenManTree(dir))
+	assert.NoError(t, cmd.GenMarkdownTree(dir))
+
+	for _, name := range []string{"mycmd.1", "mycmd-count.1", "mycmd.md", "mycmd_count.md"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err, name)
+	}
+
+	_, err := os.Stat(filepath.Join(dir, "mycmd-debug.1"))
+	assert.True(t, os.IsNotExist(err))
+}
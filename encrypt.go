@@ -0,0 +1,205 @@
+package configstruct
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const encryptedPrefix = "enc:"
+
+// EncryptionKeyEnv is the env variable with the base64 encoded key that is used if no key is set by option
+const EncryptionKeyEnv = "CONFIGSTRUCT_ENCRYPTION_KEY"
+
+// ErrNoEncryptionKey is returned if an encrypted value is found but no key is available
+var ErrNoEncryptionKey = errors.New("no encryption key")
+
+// ParseKey decodes a base64 encoded AES key with a length of 16, 24 or 32 bytes,
+// e.g. created with `openssl rand -base64 32`
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode encryption key: %w", err)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid encryption key length %d, must be 16, 24 or 32 bytes", len(key))
+	}
+}
+
+// EncryptValue encrypts a value with AES-GCM and returns it in the form enc:<base64> to be used in config files
+func EncryptValue(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", fmt.Errorf("could not create nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value of the form enc:<base64> created by EncryptValue,
+// values without enc: prefix are returned unchanged
+func DecryptValue(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("could not decode encrypted value: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt value: %w", err)
+	}
+
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrNoEncryptionKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// resolveEncryptionKey returns key if set, otherwise the key from keyFile or the env variable EncryptionKeyEnv
+func resolveEncryptionKey(key []byte, keyFile string) ([]byte, error) {
+	if len(key) > 0 {
+		return key, nil
+	}
+
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not open key file %s: %w", keyFile, err)
+		}
+		return ParseKey(string(b))
+	}
+
+	if env := os.Getenv(EncryptionKeyEnv); env != "" {
+		return ParseKey(env)
+	}
+
+	return nil, ErrNoEncryptionKey
+}
+
+// encryptionKeySet reports whether a key is set by option, key file or the env variable EncryptionKeyEnv,
+// without key enc: values are plain strings
+func encryptionKeySet(key []byte, keyFile string) bool {
+	return len(key) > 0 || keyFile != "" || os.Getenv(EncryptionKeyEnv) != ""
+}
+
+// decryptNodes replaces all encrypted string values below node with their decrypted values,
+// the key is only resolved if an encrypted value is found
+func decryptNodes(node *yaml.Node, key []byte, keyFile string) error {
+	return visitEncryptedNodes(node, func(node *yaml.Node) error {
+		if len(key) == 0 {
+			var err error
+			key, err = resolveEncryptionKey(key, keyFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		plain, err := DecryptValue(key, node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		// the decrypted value is resolved again so encrypted numbers and bools are possible,
+		// only values that would resolve to null stay strings
+		node.Value = plain
+		node.Tag = ""
+		node.Style = 0
+		if isYamlNull(plain) {
+			node.Tag = "!!str"
+		}
+
+		return nil
+	})
+}
+
+func isYamlNull(value string) bool {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return true
+	}
+
+	return false
+}
+
+// isEncryptedNode reports whether node is a string scalar with enc: prefix
+func isEncryptedNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && strings.HasPrefix(node.Value, encryptedPrefix)
+}
+
+// visitEncryptedNodes calls fn for every string scalar with enc: prefix below node
+func visitEncryptedNodes(node *yaml.Node, fn func(node *yaml.Node) error) error {
+	if node.Kind == yaml.ScalarNode {
+		if isEncryptedNode(node) {
+			return fn(node)
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := visitEncryptedNodes(child, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptSecretNodes encrypts the values of all non-empty fields tagged with secret:"true" in the encoded node of c
+func encryptSecretNodes(node *yaml.Node, c interface{}, key []byte) error {
+	var err error
+	visitSecretNodes(node, reflect.TypeOf(c), func(mapping *yaml.Node, i int) {
+		value := mapping.Content[i+1]
+		if err != nil || value.Kind != yaml.ScalarNode || value.Value == "" || value.ShortTag() == "!!null" ||
+			strings.HasPrefix(value.Value, encryptedPrefix) {
+			return
+		}
+
+		var encrypted string
+		encrypted, err = EncryptValue(key, value.Value)
+		mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: encrypted}
+	})
+
+	return err
+}
@@ -0,0 +1,271 @@
+package configstruct
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEncryptionKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestEncryptValue(t *testing.T) {
+	encrypted, err := EncryptValue(testEncryptionKey, "hunter2")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "enc:"))
+
+	decrypted, err := DecryptValue(testEncryptionKey, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", decrypted)
+
+	_, err = DecryptValue([]byte("fedcba9876543210fedcba9876543210"), encrypted)
+	assert.Error(t, err)
+
+	plain, err := DecryptValue(testEncryptionKey, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", plain)
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(testEncryptionKey) + "\n")
+	assert.NoError(t, err)
+	assert.Equal(t, testEncryptionKey, key)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestParse_EncryptedValues(t *testing.T) {
+	type Config struct {
+		Hostname string `yaml:"hostname"`
+		Password string `yaml:"password"`
+		Port     int    `yaml:"port"`
+	}
+
+	password, err := EncryptValue(testEncryptionKey, "hunter2")
+	assert.NoError(t, err)
+	port, err := EncryptValue(testEncryptionKey, "8080")
+	assert.NoError(t, err)
+
+	path := writeTestFile(t, "config.yaml", "hostname: localhost\npassword: "+password+"\nport: "+port+"\n")
+
+	t.Run("key option", func(t *testing.T) {
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf,
+			WithYamlConfig(path), WithEncryptionKey(testEncryptionKey))
+		assert.NoError(t, err)
+		assert.Equal(t, Config{Hostname: "localhost", Password: "hunter2", Port: 8080}, conf)
+	})
+
+	t.Run("key file", func(t *testing.T) {
+		keyFile := writeTestFile(t, "key", base64.StdEncoding.EncodeToString(testEncryptionKey))
+
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf,
+			WithYamlConfig(path), WithEncryptionKeyFile(keyFile))
+		assert.NoError(t, err)
+		assert.Equal(t, "hunter2", conf.Password)
+	})
+
+	t.Run("key env", func(t *testing.T) {
+		os.Setenv(EncryptionKeyEnv, base64.StdEncoding.EncodeToString(testEncryptionKey))
+		defer os.Unsetenv(EncryptionKeyEnv)
+
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, WithYamlConfig(path))
+		assert.NoError(t, err)
+		assert.Equal(t, "hunter2", conf.Password)
+	})
+
+	t.Run("null values stay strings", func(t *testing.T) {
+		nullValue, err := EncryptValue(testEncryptionKey, "null")
+		assert.NoError(t, err)
+		tildeValue, err := EncryptValue(testEncryptionKey, "~")
+		assert.NoError(t, err)
+
+		type NullConfig struct {
+			Password string `yaml:"password"`
+			Token    string `yaml:"token"`
+		}
+
+		nullPath := writeTestFile(t, "null.yaml", "password: "+nullValue+"\ntoken: "+tildeValue+"\n")
+
+		conf := NullConfig{}
+		err = ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf,
+			WithYamlConfig(nullPath), WithEncryptionKey(testEncryptionKey))
+		assert.NoError(t, err)
+		assert.Equal(t, NullConfig{Password: "null", Token: "~"}, conf)
+	})
+
+	t.Run("strict mode reports original lines", func(t *testing.T) {
+		strictPath := writeTestFile(t, "strict.yaml", "# credentials\n\npassword: "+password+"\n\n# listen port\nport: "+port+"\nlabels: {a: b}\n\nbogus: true\n")
+
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf,
+			WithYamlConfig(strictPath), WithEncryptionKey(testEncryptionKey), WithStrictConfig())
+
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []ValidationIssue{
+			{Path: "labels", Line: 7, Column: 1, Message: "unknown key"},
+			{Path: "bogus", Line: 9, Column: 1, Message: "unknown key"},
+		}, validationErr.Issues)
+	})
+
+	t.Run("without key values are plain strings", func(t *testing.T) {
+		plainPath := writeTestFile(t, "plain.yaml", "hostname: localhost\npassword: "+password+"\n")
+
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, WithYamlConfig(plainPath))
+		assert.NoError(t, err)
+		assert.Equal(t, password, conf.Password)
+	})
+
+	t.Run("invalid key file", func(t *testing.T) {
+		conf := Config{}
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf,
+			WithYamlConfig(path), WithEncryptionKeyFile(filepath.Join(t.TempDir(), "missing")))
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateFile_EncryptedValues(t *testing.T) {
+	type Config struct {
+		Password string `yaml:"password"`
+		Port     int    `yaml:"port"`
+	}
+
+	port, err := EncryptValue(testEncryptionKey, "8080")
+	assert.NoError(t, err)
+	invalidPort, err := EncryptValue(testEncryptionKey, "abc")
+	assert.NoError(t, err)
+
+	path := writeTestFile(t, "config.yaml", "port: "+port+"\n")
+	invalidPath := writeTestFile(t, "invalid.yaml", "port: "+invalidPort+"\n")
+
+	t.Run("without key", func(t *testing.T) {
+		assert.NoError(t, ValidateFile(path, &Config{}))
+		assert.NoError(t, ValidateFile(invalidPath, &Config{}))
+	})
+
+	t.Run("with key", func(t *testing.T) {
+		assert.NoError(t, ValidateFile(path, &Config{}, WithEncryptionKey(testEncryptionKey)))
+
+		err := ValidateFile(invalidPath, &Config{}, WithEncryptionKey(testEncryptionKey))
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "port", validationErr.Issues[0].Path)
+	})
+}
+
+func TestSave_WithEncryptedSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	conf := newSecretConfig()
+	err := Save(path, &conf, WithEncryptedSecrets(testEncryptionKey))
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "hunter2")
+	assert.Contains(t, string(b), "hostname: localhost\npassword: enc:")
+
+	loaded := secretConfig{}
+	err = ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &loaded,
+		WithYamlConfig(path), WithEncryptionKey(testEncryptionKey))
+	assert.NoError(t, err)
+	assert.Equal(t, conf, loaded)
+}
+
+func TestSave_PreserveEncryptedValues(t *testing.T) {
+	type Config struct {
+		Hostname string `yaml:"hostname"`
+		Password string `yaml:"password" secret:"true"`
+		Token    string `yaml:"token"`
+	}
+
+	password, err := EncryptValue(testEncryptionKey, "hunter2")
+	assert.NoError(t, err)
+	token, err := EncryptValue(testEncryptionKey, "abc")
+	assert.NoError(t, err)
+	content := "# database\nhostname: localhost\npassword: " + password + "\ntoken: " + token + "\n"
+
+	load := func(t *testing.T, path string, opts ...Option) Config {
+		conf := Config{}
+		opts = append(opts, WithYamlConfig(path))
+		err := ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, opts...)
+		assert.NoError(t, err)
+		return conf
+	}
+
+	t.Run("unchanged values keep their encrypted node", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", content)
+
+		conf := load(t, path, WithEncryptionKey(testEncryptionKey))
+		conf.Hostname = "example.com"
+		err := Save(path, &conf, WithPreserveExisting(), WithEncryptedSecrets(testEncryptionKey))
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# database\nhostname: example.com\npassword: "+password+"\ntoken: "+token+"\n", string(b))
+	})
+
+	t.Run("changed values are encrypted again", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", content)
+		os.Setenv(EncryptionKeyEnv, base64.StdEncoding.EncodeToString(testEncryptionKey))
+		defer os.Unsetenv(EncryptionKeyEnv)
+
+		conf := load(t, path)
+		conf.Password = "changed-password"
+		conf.Token = "changed-token"
+		err := Save(path, &conf, WithPreserveExisting())
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(b), "changed-")
+		assert.Contains(t, string(b), "password: enc:")
+		assert.Contains(t, string(b), "token: enc:")
+		assert.Equal(t, conf, load(t, path))
+	})
+
+	t.Run("without key plain text is not written", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", content)
+
+		conf := load(t, path)
+		assert.Equal(t, password, conf.Password)
+
+		// values that were not decrypted are written unchanged
+		err := Save(path, &conf, WithPreserveExisting())
+		assert.NoError(t, err)
+
+		conf.Token = "changed-token"
+		err = Save(path, &conf, WithPreserveExisting())
+		assert.ErrorIs(t, err, ErrNoEncryptionKey)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	})
+}
+
+func TestNewConfigCommand_Encrypt(t *testing.T) {
+	var out bytes.Buffer
+
+	keyFile := writeTestFile(t, "key", base64.StdEncoding.EncodeToString(testEncryptionKey))
+	cmd := NewCommand("", "Test CLI", nil, nil, NewConfigCommand(&secretConfig{})).SetOutput(&out)
+
+	err := cmd.ParseAndRun([]string{"cliName", "config", "encrypt", "-key-file", keyFile, "hunter2"})
+	assert.NoError(t, err)
+
+	decrypted, err := DecryptValue(testEncryptionKey, strings.TrimSpace(out.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", decrypted)
+}
@@ -0,0 +1,116 @@
+package configstruct
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// exit codes returned by ExitCode
+const (
+	ExitCodeOK    = 0
+	ExitCodeError = 1
+	ExitCodeUsage = 2
+)
+
+// ErrUnknownCommand matches every *UnknownCommandError with errors.Is
+var ErrUnknownCommand = errors.New("unknown command")
+
+// ExitCoder is implemented by errors that define the exit code of the process
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// UnknownCommandError is returned if a sub-command is referenced that is not defined
+type UnknownCommandError struct {
+	Name        string
+	Available   []string
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	msg := fmt.Sprintf("unknown command %q, available commands: %s", e.Name, strings.Join(e.Available, ", "))
+	if len(e.Suggestions) > 0 {
+		msg += ", " + didYouMean(e.Suggestions, "")
+	}
+
+	return msg
+}
+
+// Is reports whether target is ErrUnknownCommand
+func (e *UnknownCommandError) Is(target error) bool {
+	return target == ErrUnknownCommand
+}
+
+// ExitCode returns ExitCodeUsage
+func (e *UnknownCommandError) ExitCode() int {
+	return ExitCodeUsage
+}
+
+// UsageError wraps errors that are caused by a wrong usage, like undefined flags or missing arguments
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns ExitCodeUsage
+func (e *UsageError) ExitCode() int {
+	return ExitCodeUsage
+}
+
+// UnknownFlagError is returned wrapped in a *UsageError if a flag is given that is not defined
+type UnknownFlagError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *UnknownFlagError) Error() string {
+	msg := "flag provided but not defined: -" + e.Name
+	if len(e.Suggestions) > 0 {
+		msg += ", " + didYouMean(e.Suggestions, "-")
+	}
+
+	return msg
+}
+
+// HelpError is returned if help was requested with -h or -help, the usage was already printed
+type HelpError struct {
+	Command string
+}
+
+func (e *HelpError) Error() string {
+	return flag.ErrHelp.Error()
+}
+
+// Is reports whether target is flag.ErrHelp
+func (e *HelpError) Is(target error) bool {
+	return target == flag.ErrHelp
+}
+
+// ExitCode returns ExitCodeOK as requesting help is not an error for the process
+func (e *HelpError) ExitCode() int {
+	return ExitCodeOK
+}
+
+// ExitCode maps an error returned by Parse or ParseAndRun to a process exit code,
+// nil results in ExitCodeOK, errors implementing ExitCoder define their own code and all others result in ExitCodeError
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	var exitCoder ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+
+	return ExitCodeError
+}
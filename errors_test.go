@@ -0,0 +1,126 @@
+package configstruct
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownCommandError(t *testing.T) {
+	countCmd := NewCommand("count", "Count numbers", nil, nil)
+	sumCmd := NewCommand("sum", "Sum numbers", nil, nil)
+	cmd := NewCommand("", "Test CLI", nil, nil, countCmd, sumCmd).SetErrOutput(io.Discard)
+
+	err := cmd.ParseAndRun([]string{"cliName", "cuont"})
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+
+	var unknownErr *UnknownCommandError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, "cuont", unknownErr.Name)
+	assert.Equal(t, []string{"count", "sum"}, unknownErr.Available)
+	assert.Equal(t, []string{"count"}, unknownErr.Suggestions)
+	assert.Contains(t, err.Error(), `did you mean count?`)
+	assert.Equal(t, ExitCodeUsage, ExitCode(err))
+}
+
+func TestUnknownFlagError(t *testing.T) {
+	flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	conf := testConfig{}
+
+	err := ParseWithFlagSet(flagSet, []string{"command", "-hostnmae=localhost"}, &conf)
+
+	var unknownErr *UnknownFlagError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, "hostnmae", unknownErr.Name)
+	assert.Equal(t, []string{"hostname"}, unknownErr.Suggestions)
+	assert.EqualError(t, err, "flag provided but not defined: -hostnmae, did you mean -hostname?")
+
+	tests := []struct {
+		name string
+		args []string
+		flag string
+	}{
+		{name: "double dash", args: []string{"command", "--hostnmae", "localhost"}, flag: "hostnmae"},
+		{name: "double dash with value", args: []string{"command", "--prot=80"}, flag: "prot"},
+		{name: "after defined flags", args: []string{"command", "--hostname", "-x", "-debug", "--undefined"}, flag: "undefined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+			flagSet.SetOutput(io.Discard)
+
+			err := ParseWithFlagSet(flagSet, tt.args, &testConfig{})
+
+			var unknownErr *UnknownFlagError
+			assert.True(t, errors.As(err, &unknownErr))
+			assert.Equal(t, tt.flag, unknownErr.Name)
+		})
+	}
+
+	t.Run("hidden flags are not suggested", func(t *testing.T) {
+		type hiddenConfig struct {
+			Debug bool `cli:"debug" hidden:"true"`
+			Dry   bool `cli:"dry"`
+		}
+		flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
+
+		err := ParseWithFlagSet(flagSet, []string{"command", "-debu"}, &hiddenConfig{})
+
+		var unknownErr *UnknownFlagError
+		assert.True(t, errors.As(err, &unknownErr))
+		assert.NotContains(t, unknownErr.Suggestions, "debug")
+		assert.NotContains(t, unknownErr.Suggestions, "no-debug")
+	})
+
+	t.Run("flag values are not flags", func(t *testing.T) {
+		flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
+		conf := testConfig{}
+
+		err := ParseWithFlagSet(flagSet, []string{"command", "--hostname", "-x"}, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, "-x", conf.Hostname)
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	flagSet := flag.NewFlagSet("command", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	conf := testConfig{}
+	usageErr := ParseWithFlagSet(flagSet, []string{"command", "-undefined"}, &conf)
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "nil", err: nil, code: ExitCodeOK},
+		{name: "generic error", err: errors.New("failed"), code: ExitCodeError},
+		{name: "usage error", err: usageErr, code: ExitCodeUsage},
+		{name: "custom exit coder", err: &customExitError{code: 42}, code: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, ExitCode(tt.err))
+		})
+	}
+}
+
+type customExitError struct {
+	code int
+}
+
+func (e *customExitError) Error() string {
+	return "custom"
+}
+
+func (e *customExitError) ExitCode() int {
+	return e.code
+}
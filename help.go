@@ -0,0 +1,567 @@
+package configstruct
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const defaultHelpWidth = 80
+
+// SetLongDescription sets a detailed description that is shown in the help of this command
+func (c *Command) SetLongDescription(description string) *Command {
+	c.longDescription = description
+	return c
+}
+
+// SetExamples sets example calls that are shown in the help of this command
+func (c *Command) SetExamples(examples ...string) *Command {
+	c.examples = examples
+	return c
+}
+
+// SetGroup sets the group the command is listed under in the help of its parent command
+func (c *Command) SetGroup(group string) *Command {
+	c.group = group
+	return c
+}
+
+// Name returns the name of the command
+func (c *Command) Name() string {
+	return c.fs.Name()
+}
+
+// Description returns the short description of the command
+func (c *Command) Description() string {
+	return c.description
+}
+
+// CommandPath returns the names of all commands from the root to this command, an empty root name
+// is replaced by the name of the executable
+func (c *Command) CommandPath() string {
+	names := make([]string, 0)
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		name := cmd.fs.Name()
+		if name == "" && cmd.rootCommand == nil {
+			name = filepath.Base(os.Args[0])
+		}
+		names = append([]string{name}, names...)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// usageLine returns the synopsis of the command like "mycmd count [flags] <filename>"
+func (c *Command) usageLine() string {
+	line := c.CommandPath()
+	if c.hasVisibleFlags() {
+		line += " [flags]"
+	}
+	if len(c.subCommandNames()) > 0 {
+		line += " <command>"
+	}
+	for _, arg := range c.structArgs() {
+		if arg.required {
+			line += " <" + arg.name + ">"
+		} else {
+			line += " [" + arg.name + "]"
+		}
+	}
+
+	return line
+}
+
+// structFlags returns all flags of this command, persistent flags of parent commands are returned separately
+func (c *Command) structFlags() (own []structFlag, inherited []structFlag) {
+	if c.config != nil {
+		own = getStructFlags(c.config)
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range own {
+		seen[f.name] = true
+	}
+
+	for parent := c.rootCommand; parent != nil; parent = parent.rootCommand {
+		if parent.config == nil {
+			continue
+		}
+		for _, f := range getStructFlags(parent.config) {
+			if f.persistent && !seen[f.name] {
+				seen[f.name] = true
+				inherited = append(inherited, f)
+			}
+		}
+	}
+
+	return own, inherited
+}
+
+// structArgs returns all positional arguments of this command
+func (c *Command) structArgs() []structArg {
+	if c.config == nil {
+		return nil
+	}
+
+	return getStructArgs(c.config)
+}
+
+func (c *Command) hasVisibleFlags() bool {
+	own, inherited := c.structFlags()
+	for _, f := range append(own, inherited...) {
+		if !f.hidden {
+			return true
+		}
+	}
+
+	return false
+}
+
+const helpCommandName = "help"
+
+// isHelpCommand reports whether args reference the built-in help command, it is available for all commands
+// with sub-commands unless one of them is named help
+func (c *Command) isHelpCommand(args []string, prefixMatching bool) bool {
+	return len(c.subCommands) > 0 && len(args) > 0 && args[0] == helpCommandName &&
+		c.findSubCommand(args, prefixMatching) == nil
+}
+
+// runHelpCommand prints the help of the command referenced by the path of sub-command names in args
+func (c *Command) runHelpCommand(args []string, prefixMatching bool) error {
+	cmd := c
+	for i := range args {
+		sub := cmd.findSubCommand(args[i:], prefixMatching)
+		if sub == nil {
+			return &UnknownCommandError{
+				Name:        args[i],
+				Available:   cmd.subCommandNames(),
+				Suggestions: suggest(args[i], cmd.subCommandNames()),
+			}
+		}
+		cmd = sub
+	}
+
+	cmd.printUsage(c.Output())
+	return nil
+}
+
+// DefaultHelpTemplate is the text/template used to render the help of a command if no other template is set
+const DefaultHelpTemplate = `{{with .Description}}{{wrap 0 .}}
+
+{{end}}{{with .LongDescription}}{{wrap 0 .}}
+
+{{end}}Usage:
+  {{.UsageLine}}
+{{- if .Aliases}}
+
+Aliases:
+  {{join .Aliases ", "}}
+{{- end}}
+{{- range .CommandGroups}}
+
+{{.Title}}:
+{{- range .Commands}}
+  {{pad .Name $.CommandWidth}}  {{wrap (add $.CommandWidth 4) .Description}}
+{{- end}}
+{{- end}}
+{{- range .FlagGroups}}
+
+{{.Title}}:
+{{- range .Flags}}
+  {{.Synopsis}}
+{{- with .Details}}
+      {{wrap 6 .}}
+{{- end}}
+{{- with .Sources}}
+      {{wrap 6 .}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Args}}
+
+Arguments:
+{{- range .Args}}
+  {{.Name}}
+{{- with .Details}}
+      {{wrap 6 .}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Examples}}
+
+Examples:
+{{- range .Examples}}
+  {{indent 2 .}}
+{{- end}}
+{{- end}}
+`
+
+var defaultHelpTemplate = template.Must(newHelpTemplate(DefaultHelpTemplate))
+
+// HelpData is passed to help templates and describes a command with its sub-commands, flags and arguments
+type HelpData struct {
+	Command         *Command
+	Name            string
+	Path            string
+	Description     string
+	LongDescription string
+	UsageLine       string
+	Aliases         []string
+	Examples        []string
+	CommandGroups   []HelpCommandGroup
+	CommandWidth    int
+	FlagGroups      []HelpFlagGroup
+	Args            []HelpArg
+	Width           int
+}
+
+// HelpCommandGroup is a titled section of sub-commands
+type HelpCommandGroup struct {
+	Title    string
+	Commands []HelpCommand
+}
+
+// HelpCommand describes a visible sub-command
+type HelpCommand struct {
+	Command     *Command
+	Name        string
+	Description string
+	Aliases     []string
+}
+
+// HelpFlagGroup is a titled section of flags
+type HelpFlagGroup struct {
+	Title string
+	Flags []HelpFlag
+}
+
+// HelpFlag describes a visible flag
+type HelpFlag struct {
+	Name     string
+	Alt      string
+	Type     string
+	Usage    string
+	Default  string
+	Env      string
+	YamlKey  string
+	Required bool
+	IsBool   bool
+}
+
+// Synopsis returns the names of the flag with its type, e.g. "-port, -p int"
+func (f HelpFlag) Synopsis() string {
+	names := "-" + f.Name
+	if f.IsBool {
+		names = "-[no-]" + f.Name
+	}
+	if f.Alt != "" {
+		names += ", -" + f.Alt
+	}
+	if f.Type != "" {
+		names += " " + f.Type
+	}
+
+	return names
+}
+
+// Details returns the usage of the flag with required marker and default value
+func (f HelpFlag) Details() string {
+	details := make([]string, 0)
+	if f.Usage != "" {
+		details = append(details, f.Usage)
+	}
+	if f.Required {
+		details = append(details, "(required)")
+	}
+	if f.Default != "" {
+		details = append(details, "(default "+f.Default+")")
+	}
+
+	return strings.Join(details, " ")
+}
+
+// Sources returns the env name and config file key of the flag
+func (f HelpFlag) Sources() string {
+	sources := make([]string, 0)
+	if f.Env != "" {
+		sources = append(sources, "env "+f.Env)
+	}
+	if f.YamlKey != "" {
+		sources = append(sources, "config "+f.YamlKey)
+	}
+
+	return strings.Join(sources, ", ")
+}
+
+// HelpArg describes a positional argument
+type HelpArg struct {
+	Position int
+	Name     string
+	Usage    string
+	Required bool
+}
+
+// Details returns the usage of the argument with required marker
+func (a HelpArg) Details() string {
+	if a.Required {
+		return strings.TrimSpace(a.Usage + " (required)")
+	}
+
+	return a.Usage
+}
+
+// SetHelpTemplate sets a text/template that renders the help of this command and all sub-commands
+// that don't set their own, the template gets a HelpData value
+func (c *Command) SetHelpTemplate(text string) error {
+	tmpl, err := newHelpTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	c.helpTemplate = tmpl
+	return nil
+}
+
+// SetDefaultHelpTemplate sets the text/template that is used for all commands without their own template
+func SetDefaultHelpTemplate(text string) error {
+	tmpl, err := newHelpTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	defaultHelpTemplate = tmpl
+	return nil
+}
+
+func newHelpTemplate(text string) (*template.Template, error) {
+	return template.New("help").Funcs(template.FuncMap{
+		"wrap": func(indent int, text string) string {
+			return wrapText(text, indent, helpWidth())
+		},
+		"indent": func(indent int, text string) string {
+			return strings.Replace(strings.TrimRight(text, "\n"), "\n", "\n"+strings.Repeat(" ", indent), -1)
+		},
+		"pad": func(text string, width int) string {
+			return fmt.Sprintf("%-*s", width, text)
+		},
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"join": strings.Join,
+	}).Parse(text)
+}
+
+// printUsage writes the help of this command to w
+func (c *Command) printUsage(w io.Writer) {
+	tmpl := defaultHelpTemplate
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		if cmd.helpTemplate != nil {
+			tmpl = cmd.helpTemplate
+			break
+		}
+	}
+
+	if err := tmpl.Execute(w, c.helpData()); err != nil {
+		fmt.Fprintf(w, "could not render help: %s\n", err)
+	}
+}
+
+// helpData collects all information about this command that is shown in the help
+func (c *Command) helpData() HelpData {
+	data := HelpData{
+		Command:         c,
+		Name:            c.fs.Name(),
+		Path:            c.CommandPath(),
+		Description:     c.description,
+		LongDescription: c.longDescription,
+		UsageLine:       c.usageLine(),
+		Examples:        c.examples,
+		Width:           helpWidth(),
+	}
+
+	if len(c.aliases) > 0 {
+		data.Aliases = append([]string{c.fs.Name()}, c.aliases...)
+	}
+
+	groupIndex := make(map[string]int)
+	for _, sub := range c.subCommands {
+		if sub.hidden {
+			continue
+		}
+
+		i, ok := groupIndex[sub.group]
+		if !ok {
+			title := "Available Commands"
+			if sub.group != "" {
+				title = sub.group + " Commands"
+			}
+			i = len(data.CommandGroups)
+			groupIndex[sub.group] = i
+			data.CommandGroups = append(data.CommandGroups, HelpCommandGroup{Title: title})
+		}
+
+		data.CommandGroups[i].Commands = append(data.CommandGroups[i].Commands, HelpCommand{
+			Command:     sub,
+			Name:        sub.fs.Name(),
+			Description: sub.description,
+			Aliases:     sub.aliases,
+		})
+		if len(sub.fs.Name()) > data.CommandWidth {
+			data.CommandWidth = len(sub.fs.Name())
+		}
+	}
+
+	if len(data.CommandGroups) > 0 && c.findSubCommand([]string{helpCommandName}, false) == nil {
+		data.CommandGroups[0].Commands = append(data.CommandGroups[0].Commands, HelpCommand{
+			Name:        helpCommandName,
+			Description: "Help about any command",
+		})
+		if len(helpCommandName) > data.CommandWidth {
+			data.CommandWidth = len(helpCommandName)
+		}
+	}
+
+	own, inherited := c.structFlags()
+	groupIndex = make(map[string]int)
+	for _, f := range own {
+		if f.hidden {
+			continue
+		}
+
+		i, ok := groupIndex[f.group]
+		if !ok {
+			title := "Flags"
+			if f.group != "" {
+				title = f.group + " Flags"
+			}
+			i = len(data.FlagGroups)
+			groupIndex[f.group] = i
+			data.FlagGroups = append(data.FlagGroups, HelpFlagGroup{Title: title})
+		}
+
+		data.FlagGroups[i].Flags = append(data.FlagGroups[i].Flags, c.helpFlag(f))
+	}
+
+	global := HelpFlagGroup{Title: "Global Flags"}
+	for _, f := range inherited {
+		if !f.hidden {
+			global.Flags = append(global.Flags, c.helpFlag(f))
+		}
+	}
+	if len(global.Flags) > 0 {
+		data.FlagGroups = append(data.FlagGroups, global)
+	}
+
+	for _, arg := range c.structArgs() {
+		data.Args = append(data.Args, HelpArg{
+			Position: arg.position,
+			Name:     arg.name,
+			Usage:    arg.description,
+			Required: arg.required,
+		})
+	}
+
+	return data
+}
+
+// helpFlag converts a struct flag to its help representation
+func (c *Command) helpFlag(f structFlag) HelpFlag {
+	return HelpFlag{
+		Name:     f.name,
+		Alt:      f.alt,
+		Type:     flagType(f),
+		Usage:    f.description,
+		Default:  c.flagDefault(f),
+		Env:      f.env,
+		YamlKey:  f.yamlKey,
+		Required: f.required,
+		IsBool:   f.kind == reflect.Bool,
+	}
+}
+
+// flagDefault returns the formatted default value of a flag or an empty string for zero values, secrets are masked
+func (c *Command) flagDefault(f structFlag) string {
+	def := fmt.Sprint(f.defaultValue)
+	if fl := c.fs.Lookup(f.name); fl != nil {
+		def = fl.DefValue
+	} else if f.kind == reflect.Slice {
+		def = (&structSliceFlag{target: reflect.ValueOf(f.defaultValue)}).String()
+	}
+
+	switch def {
+	case "", "0", "false", "[]", "null":
+		return ""
+	}
+
+	if f.secret {
+		return secretMask
+	}
+
+	if f.kind == reflect.String {
+		return strconv.Quote(def)
+	}
+
+	return def
+}
+
+// flagType returns the type name of a flag shown in the help, count flags have none as they are used like bools
+func flagType(f structFlag) string {
+	if f.count {
+		return ""
+	}
+
+	switch f.kind {
+	case reflect.String:
+		return "string"
+	case reflect.Int:
+		return "int"
+	case reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "json"
+	}
+
+	return ""
+}
+
+// helpWidth returns the width of the terminal taken from the COLUMNS env variable
+func helpWidth() int {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		return defaultHelpWidth
+	}
+
+	return width
+}
+
+// wrapText wraps text to the given width, continuation lines are indented by indent spaces
+func wrapText(text string, indent int, width int) string {
+	lineWidth := width - indent
+	if lineWidth < 20 {
+		lineWidth = 20
+	}
+
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(line)+1+len(word) > lineWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"+strings.Repeat(" ", indent))
+}
@@ -0,0 +1,113 @@
+package configstruct
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type helpRootConfig struct {
+	Hostname string `env:"CONFIGSTRUCT_HOSTNAME" cli:"hostname" yaml:"host" persistent:"true" usage:"hostname value"`
+	Port     int    `cli:"port" cliAlt:"p" group:"Network" usage:"listen port"`
+}
+
+type helpCountConfig struct {
+	Number   int    `cli:"number" usage:"number to count"`
+	Verbose  int    `cli:"verbose" cliAlt:"v" count:"true" usage:"verbosity level"`
+	Filename string `arg:"1" name:"filename" required:"true" usage:"file to count"`
+}
+
+func TestCommand_Help(t *testing.T) {
+	var out bytes.Buffer
+
+	countCmd := NewCommand("count", "Count numbers", &helpCountConfig{}, nil).
+		SetLongDescription("Counts all numbers in the given file.").
+		SetAliases("cnt").
+		SetExamples("mycmd count -number=2 numbers.txt")
+	sumCmd := NewCommand("sum", "Sum numbers", nil, nil).SetGroup("Math")
+	cmd := NewCommand("", "Test CLI", &helpRootConfig{Hostname: "localhost", Port: 8080}, nil, countCmd, sumCmd).SetOutput(&out)
+
+	cmd.printUsage(&out)
+	help := out.String()
+	assert.Contains(t, help, "Available Commands:\n  count  Count numbers\n")
+	assert.Contains(t, help, "Math Commands:\n  sum    Sum numbers\n")
+	assert.Contains(t, help, "  -hostname string\n      hostname value (default \"localhost\")\n      env CONFIGSTRUCT_HOSTNAME, config host\n")
+	assert.Contains(t, help, "Network Flags:\n  -port, -p int\n      listen port (default 8080)\n")
+
+	out.Reset()
+	countCmd.printUsage(&out)
+	help = out.String()
+	assert.Contains(t, help, "Counts all numbers in the given file.")
+	assert.Contains(t, help, " count [flags] <filename>\n")
+	assert.Contains(t, help, "Aliases:\n  count, cnt\n")
+	assert.Contains(t, help, "  -verbose, -v\n      verbosity level\n")
+	assert.Contains(t, help, "Global Flags:\n  -hostname string\n")
+	assert.Contains(t, help, "Arguments:\n  filename\n      file to count (required)\n")
+	assert.Contains(t, help, "Examples:\n  mycmd count -number=2 numbers.txt\n")
+}
+
+func TestCommand_SetHelpTemplate(t *testing.T) {
+	var out bytes.Buffer
+
+	countCmd := NewCommand("count", "Count numbers", &helpCountConfig{}, nil)
+	cmd := NewCommand("", "Test CLI", &helpRootConfig{Hostname: "localhost"}, nil, countCmd)
+
+	err := cmd.SetHelpTemplate("{{.Name}}:{{range .CommandGroups}}{{range .Commands}} {{.Name}}{{end}}{{end}}")
+	assert.NoError(t, err)
+	cmd.printUsage(&out)
+	assert.Equal(t, ": count help", out.String())
+
+	// sub-commands use the template of their parent
+	out.Reset()
+	err = cmd.SetHelpTemplate(`{{range .FlagGroups}}{{range .Flags}}{{.Name}}={{.Default}} {{.Env}} {{.YamlKey}};{{end}}{{end}}{{range .Args}}{{.Name}}{{end}}`)
+	assert.NoError(t, err)
+	countCmd.printUsage(&out)
+	assert.Equal(t, `number=  number;verbose=  verbose;hostname="localhost" CONFIGSTRUCT_HOSTNAME host;filename`, out.String())
+
+	err = cmd.SetHelpTemplate("{{.Undefined")
+	assert.Error(t, err)
+}
+
+func TestSetDefaultHelpTemplate(t *testing.T) {
+	defer SetDefaultHelpTemplate(DefaultHelpTemplate)
+
+	var out bytes.Buffer
+	err := SetDefaultHelpTemplate("{{.Description}} ({{.Path}})")
+	assert.NoError(t, err)
+
+	cmd := NewCommand("count", "Count numbers", nil, nil)
+	cmd.printUsage(&out)
+	assert.Equal(t, "Count numbers (count)", out.String())
+}
+
+func TestCommand_HelpCommand(t *testing.T) {
+	var out bytes.Buffer
+
+	addCmd := NewCommand("add", "Add a remote", nil, nil)
+	remoteCmd := NewCommand("remote", "Manage remotes", nil, nil, addCmd)
+	runs := 0
+	cmd := NewCommand("", "Test CLI", nil, func(cmd *Command, cfg interface{}) error {
+		runs++
+		return nil
+	}, remoteCmd).SetOutput(&out)
+
+	err := cmd.ParseAndRun([]string{"cliName", "help", "remote", "add"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, runs)
+	assert.True(t, strings.HasPrefix(out.String(), "Add a remote\n"))
+
+	out.Reset()
+	err = cmd.ParseAndRun([]string{"cliName", "help"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Available Commands:\n  remote  Manage remotes\n  help    Help about any command\n")
+
+	err = cmd.ParseAndRun([]string{"cliName", "help", "remote", "ad"})
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, "short text", wrapText("short text", 4, 80))
+	assert.Equal(t, "aaaa bbbb cccc dddd\n  eeee", wrapText("aaaa bbbb cccc dddd eeee", 2, 22))
+}
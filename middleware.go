@@ -0,0 +1,67 @@
+package configstruct
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a CommandFunc to run code before and after it
+type Middleware func(next CommandFunc) CommandFunc
+
+// PanicError is returned by the Recover middleware if a command function panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("command panicked: %v", e.Value)
+}
+
+// Use adds middlewares to this command, they wrap the command function of this command and of all its sub-commands.
+// Middlewares of parent commands wrap those of sub-commands, the first middleware is the outermost
+func (c *Command) Use(mw ...Middleware) *Command {
+	c.middlewares = append(c.middlewares, mw...)
+	return c
+}
+
+// handler returns the command function wrapped by all middlewares of this command and its parents
+func (c *Command) handler() CommandFunc {
+	f := c.f
+	for cmd := c; cmd != nil; cmd = cmd.rootCommand {
+		for i := len(cmd.middlewares) - 1; i >= 0; i-- {
+			f = cmd.middlewares[i](f)
+		}
+	}
+
+	return f
+}
+
+// Recover returns a middleware that turns a panic in the command function into a *PanicError
+func Recover() Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, cfg interface{}) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+
+			return next(c, cfg)
+		}
+	}
+}
+
+// Timing returns a middleware that reports the execution time of the command function
+func Timing(report func(c *Command, d time.Duration)) Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, cfg interface{}) error {
+			start := time.Now()
+			err := next(c, cfg)
+			report(c, time.Since(start))
+
+			return err
+		}
+	}
+}
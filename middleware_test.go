@@ -0,0 +1,58 @@
+package configstruct
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Use(t *testing.T) {
+	calls := make([]string, 0)
+	trace := func(name string) Middleware {
+		return func(next CommandFunc) CommandFunc {
+			return func(c *Command, cfg interface{}) error {
+				calls = append(calls, name+":"+c.fs.Name())
+				return next(c, cfg)
+			}
+		}
+	}
+
+	countCmd := NewCommand("count", "Count numbers", nil, func(cmd *Command, cfg interface{}) error {
+		calls = append(calls, "run:count")
+		return nil
+	}).Use(trace("sub"))
+	cmd := NewCommand("", "Test CLI", nil, nil, countCmd).Use(trace("first"), trace("second"))
+
+	err := cmd.ParseAndRun([]string{"cliName", "count"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first:count", "second:count", "sub:count", "run:count"}, calls)
+}
+
+func TestRecover(t *testing.T) {
+	cmd := NewCommand("", "Test CLI", nil, func(cmd *Command, cfg interface{}) error {
+		panic("boom")
+	}).Use(Recover())
+
+	err := cmd.ParseAndRun([]string{"cliName"})
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+}
+
+func TestTiming(t *testing.T) {
+	var duration time.Duration
+	cmd := NewCommand("", "Test CLI", nil, func(cmd *Command, cfg interface{}) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}).Use(Timing(func(c *Command, d time.Duration) {
+		duration = d
+	}))
+
+	err := cmd.ParseAndRun([]string{"cliName"})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, duration, 5*time.Millisecond)
+}
@@ -1,8 +1,39 @@
 package configstruct
 
+import (
+	"os"
+	"time"
+)
+
 type config struct {
-	precedenceEnv bool
-	file          string
+	precedenceEnv     bool
+	file              string
+	envPrefix         string
+	shutdownTimeout   time.Duration
+	leafOnly          bool
+	prefixMatching    bool
+	strictConfig      bool
+	encryptionKey     []byte
+	encryptionKeyFile string
+	// sharedConfigs are all config structs of a command tree that read the same config file
+	sharedConfigs []interface{}
+	// hiddenFlags are flags defined outside the config struct that are not shown in usage and suggestions
+	hiddenFlags map[string]bool
+}
+
+// newConfig applies all options to a fresh config
+func newConfig(opts ...Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// envName returns the name of the env variable for an env tag value
+func (c config) envName(env string) string {
+	return c.envPrefix + env
 }
 
 // Option is a config setting function
@@ -28,3 +59,142 @@ func WithYamlConfig(path string) Option {
 		c.file = path
 	}
 }
+
+// WithEnvPrefix sets a prefix that is prepended to all env names defined by env tags
+func WithEnvPrefix(prefix string) Option {
+	return func(c *config) {
+		c.envPrefix = prefix
+	}
+}
+
+// WithShutdownTimeout sets the time a command function gets to return after its context was cancelled,
+// if it takes longer ParseAndRunContext returns ErrShutdownTimeout. A zero timeout waits forever (default)
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// WithRunLeafOnly only executes the function of the deepest matched command, parent commands
+// just parse their config and run their hooks
+func WithRunLeafOnly() Option {
+	return func(c *config) {
+		c.leafOnly = true
+	}
+}
+
+// WithPrefixMatching allows to reference sub-commands by a unique prefix of their name or alias
+func WithPrefixMatching() Option {
+	return func(c *config) {
+		c.prefixMatching = true
+	}
+}
+
+// WithStrictConfig rejects config files that contain keys without a matching struct field
+func WithStrictConfig() Option {
+	return func(c *config) {
+		c.strictConfig = true
+	}
+}
+
+// withSharedConfigs sets the config structs of all commands that share the config file,
+// strict mode accepts the keys of all of them
+func withSharedConfigs(configs []interface{}) Option {
+	return func(c *config) {
+		c.sharedConfigs = configs
+	}
+}
+
+// withHiddenFlags sets the names of flags that are defined on the FlagSet by someone else and must stay hidden,
+// e.g. hidden persistent flags of parent commands
+func withHiddenFlags(names map[string]bool) Option {
+	return func(c *config) {
+		c.hiddenFlags = names
+	}
+}
+
+// WithEncryptionKey sets the AES key to decrypt enc: values in config files
+func WithEncryptionKey(key []byte) Option {
+	return func(c *config) {
+		c.encryptionKey = key
+	}
+}
+
+// WithEncryptionKeyFile reads the base64 encoded AES key to decrypt enc: values in config files from a file
+func WithEncryptionKeyFile(path string) Option {
+	return func(c *config) {
+		c.encryptionKeyFile = path
+	}
+}
+
+type saveConfig struct {
+	preserveExisting bool
+	fileMode         os.FileMode
+	sync             bool
+	backups          int
+	onlyChanged      bool
+	defaults         interface{}
+	excludeSecrets   bool
+	encryptionKey    []byte
+}
+
+// SaveOption is a setting function for Save
+type SaveOption func(s *saveConfig)
+
+// WithPreserveExisting updates an existing config file in place, comments, ordering and keys
+// without matching struct field are kept and only changed values are replaced, encrypted values stay encrypted
+func WithPreserveExisting() SaveOption {
+	return func(s *saveConfig) {
+		s.preserveExisting = true
+	}
+}
+
+// WithFileMode sets the mode of the config file and its backups, it overrides the mode of an existing file
+func WithFileMode(mode os.FileMode) SaveOption {
+	return func(s *saveConfig) {
+		s.fileMode = mode
+	}
+}
+
+// WithSync flushes the config file to disk before it replaces the old one and the directory after the rename
+func WithSync() SaveOption {
+	return func(s *saveConfig) {
+		s.sync = true
+	}
+}
+
+// WithBackups keeps up to n rotated copies of the previous config file as path.1 (newest) to path.n
+func WithBackups(n int) SaveOption {
+	return func(s *saveConfig) {
+		s.backups = n
+	}
+}
+
+// WithOnlyChanged writes only values that differ from the default tags (or zero values) of the config struct
+func WithOnlyChanged() SaveOption {
+	return func(s *saveConfig) {
+		s.onlyChanged = true
+	}
+}
+
+// WithDefaults writes only values that differ from the given defaults instance of the config struct
+func WithDefaults(defaults interface{}) SaveOption {
+	return func(s *saveConfig) {
+		s.onlyChanged = true
+		s.defaults = defaults
+	}
+}
+
+// WithoutSecrets leaves out all fields tagged with secret:"true", keys of an existing file are kept with WithPreserveExisting
+func WithoutSecrets() SaveOption {
+	return func(s *saveConfig) {
+		s.excludeSecrets = true
+	}
+}
+
+// WithEncryptedSecrets writes the values of all fields tagged with secret:"true" encrypted with the given AES key
+func WithEncryptedSecrets(key []byte) SaveOption {
+	return func(s *saveConfig) {
+		s.encryptionKey = key
+	}
+}
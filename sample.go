@@ -0,0 +1,180 @@
+package configstruct
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatYAML is the format of YAML config files
+const FormatYAML = "yaml"
+
+// GenerateSample writes a complete example config file for the config struct c to w. Every key is preceded by
+// a comment with its usage text, env name, cli flag and default value. Pre-set values of c are used as values.
+// Only FormatYAML is supported as other formats can't contain comments
+func GenerateSample(w io.Writer, c interface{}, format string) error {
+	if format != FormatYAML {
+		return fmt.Errorf("sample config format %s not supported", format)
+	}
+
+	valueRef := reflect.ValueOf(c)
+	if valueRef.Kind() == reflect.Ptr {
+		valueRef = valueRef.Elem()
+	}
+	if valueRef.Kind() != reflect.Struct {
+		return fmt.Errorf("config type %s is not a struct", valueRef.Type())
+	}
+
+	node, err := sampleStructNode(valueRef)
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	defer encoder.Close()
+
+	err = encoder.Encode(node)
+	if err != nil {
+		return fmt.Errorf("could not encode sample config: %w", err)
+	}
+
+	return nil
+}
+
+// sampleStructNode returns a mapping node with a commented key for every field of the struct value
+func sampleStructNode(value reflect.Value) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	err := addSampleFields(node, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func addSampleFields(node *yaml.Node, value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldValue := value.Field(i)
+
+		if isInlineField(field) {
+			inlineValue := reflect.Indirect(fieldValue)
+			if !inlineValue.IsValid() {
+				inlineValue = reflect.New(field.Type.Elem()).Elem()
+			}
+			if err := addSampleFields(node, inlineValue); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+
+		def, err := fieldDefault(field, fieldValue)
+		if err != nil {
+			return err
+		}
+
+		if tag, ok := field.Tag.Lookup("default"); ok && fieldValue.IsZero() {
+			fieldValue = reflect.New(field.Type).Elem()
+			if err := decodeTagValue(tag, fieldValue); err != nil {
+				return fmt.Errorf("invalid default value for field %s: %w", field.Name, err)
+			}
+		}
+
+		// secrets are neither written as value nor as default
+		if isSecretField(field) {
+			def = nil
+			fieldValue = reflect.New(field.Type).Elem()
+		}
+
+		valueNode, err := sampleValueNode(fieldValue)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, HeadComment: sampleComment(field, def)}
+		node.Content = append(node.Content, keyNode, valueNode)
+	}
+
+	return nil
+}
+
+// sampleValueNode returns the node for a field value, nested structs are commented as well and
+// empty struct slices get one example element
+func sampleValueNode(value reflect.Value) (*yaml.Node, error) {
+	switch value.Kind() {
+	case reflect.Struct:
+		if _, ok := value.Interface().(yaml.Marshaler); !ok {
+			return sampleStructNode(value)
+		}
+	case reflect.Ptr:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			if value.IsNil() {
+				return sampleStructNode(reflect.New(value.Type().Elem()).Elem())
+			}
+			return sampleStructNode(value.Elem())
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			node := &yaml.Node{Kind: yaml.SequenceNode}
+			items := value
+			if value.Len() == 0 {
+				items = reflect.MakeSlice(value.Type(), 1, 1)
+			}
+			for i := 0; i < items.Len(); i++ {
+				item, err := sampleStructNode(items.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, item)
+			}
+			return node, nil
+		}
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(value.Interface()); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// sampleComment returns the comment for a field with usage, env name, cli flags and default
+func sampleComment(field reflect.StructField, def interface{}) string {
+	lines := make([]string, 0)
+	if usage := field.Tag.Get("usage"); usage != "" {
+		lines = append(lines, usage)
+	}
+
+	sources := make([]string, 0)
+	if env := field.Tag.Get("env"); env != "" {
+		sources = append(sources, "env: "+env)
+	}
+	if cli := field.Tag.Get("cli"); cli != "" {
+		flags := "-" + cli
+		if cliAlt := field.Tag.Get("cliAlt"); cliAlt != "" {
+			flags += ", -" + cliAlt
+		}
+		sources = append(sources, "flag: "+flags)
+	}
+	if len(sources) > 0 {
+		lines = append(lines, strings.Join(sources, ", "))
+	}
+
+	if def != nil && reflect.Indirect(reflect.ValueOf(def)).Kind() != reflect.Map &&
+		reflect.Indirect(reflect.ValueOf(def)).Kind() != reflect.Slice {
+		lines = append(lines, fmt.Sprintf("default: %v", def))
+	}
+
+	return strings.Join(lines, "\n")
+}
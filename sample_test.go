@@ -0,0 +1,55 @@
+package configstruct
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type sampleConfig struct {
+	Hostname  string       `env:"CONFIGSTRUCT_HOSTNAME" cli:"hostname" yaml:"host" usage:"hostname value"`
+	Port      int          `env:"CONFIGSTRUCT_PORT" cli:"port" cliAlt:"p" yaml:"port_num" default:"8000" usage:"listen port"`
+	Endpoints []endpoint   `cli:"endpoints" yaml:"endpoints" usage:"configured endpoints"`
+	Log       schemaNested `yaml:"log"`
+}
+
+func TestGenerateSample(t *testing.T) {
+	var out bytes.Buffer
+
+	err := GenerateSample(&out, &sampleConfig{Hostname: "localhost"}, FormatYAML)
+	assert.NoError(t, err)
+
+	expected := `# hostname value
+# env: CONFIGSTRUCT_HOSTNAME, flag: -hostname
+# default: localhost
+host: localhost
+# listen port
+# env: CONFIGSTRUCT_PORT, flag: -port, -p
+# default: 8000
+port_num: 8000
+# configured endpoints
+# flag: -endpoints
+endpoints:
+    - user: ""
+      pass: ""
+      url: ""
+log:
+    # log level
+    # default: info
+    level: info
+`
+	assert.Equal(t, expected, out.String())
+
+	// the sample can be loaded again
+	var conf sampleConfig
+	assert.NoError(t, yaml.Unmarshal(out.Bytes(), &conf))
+	assert.Equal(t, 8000, conf.Port)
+	assert.Equal(t, "info", conf.Log.Level)
+}
+
+func TestGenerateSample_UnsupportedFormat(t *testing.T) {
+	err := GenerateSample(&bytes.Buffer{}, &sampleConfig{}, "json")
+	assert.Error(t, err)
+}
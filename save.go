@@ -0,0 +1,483 @@
+package configstruct
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultYamlIndent = 4
+	defaultFileMode   = 0644
+	secretFileMode    = 0600
+)
+
+// Save writes the given config struct as YAML to a file
+func Save(path string, c interface{}, opts ...SaveOption) error {
+	cfg := saveConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// a symlinked config file is replaced at its target so the link is kept
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	var doc yaml.Node
+	err := doc.Encode(c)
+	if err != nil {
+		return fmt.Errorf("could not encode yaml config %s: %w", path, err)
+	}
+
+	if cfg.excludeSecrets {
+		removeSecretNodes(&doc, reflect.TypeOf(c))
+	}
+	if len(cfg.encryptionKey) > 0 {
+		err = encryptSecretNodes(&doc, c, cfg.encryptionKey)
+		if err != nil {
+			return fmt.Errorf("could not encrypt secrets of config %s: %w", path, err)
+		}
+	}
+
+	indent := defaultYamlIndent
+	out := &doc
+
+	var changed *yaml.Node
+	if cfg.onlyChanged {
+		changed, err = changedNode(c, cfg.defaults)
+		if err != nil {
+			return fmt.Errorf("could not compare config %s with defaults: %w", path, err)
+		}
+		if cfg.excludeSecrets {
+			removeSecretNodes(changed, reflect.TypeOf(c))
+		}
+		if len(cfg.encryptionKey) > 0 {
+			err = encryptSecretNodes(changed, c, cfg.encryptionKey)
+			if err != nil {
+				return fmt.Errorf("could not encrypt secrets of config %s: %w", path, err)
+			}
+		}
+		out = changed
+	}
+
+	if cfg.preserveExisting {
+		existing, found, err := readYamlDocument(path)
+		if err != nil {
+			return err
+		}
+		if found {
+			// encrypted values of the existing file are compared and updated with the key of the secrets or from env
+			key := cfg.encryptionKey
+			if len(key) == 0 && encryptionKeySet(nil, "") {
+				key, err = resolveEncryptionKey(nil, "")
+				if err != nil {
+					return err
+				}
+			}
+
+			err = mergeYamlNodes(existing.doc.Content[0], &doc, changed, key)
+			if err != nil {
+				return fmt.Errorf("could not update config file %s: %w", path, err)
+			}
+			out = existing.doc
+			indent = existing.indent
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+
+	err = encoder.Encode(out)
+	if err != nil {
+		return fmt.Errorf("could not encode yaml config %s: %w", path, err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return fmt.Errorf("could not encode yaml config %s: %w", path, err)
+	}
+
+	secrets := hasSecretFields(reflect.TypeOf(c))
+	mode := os.FileMode(defaultFileMode)
+	if secrets {
+		mode = secretFileMode
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+		// an existing file must not stay readable for others if it contains secrets
+		if secrets {
+			mode &^= 0077
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("could not stat config file %s: %w", path, err)
+	}
+
+	if cfg.fileMode != 0 {
+		mode = cfg.fileMode
+	}
+
+	if err == nil {
+		err = rotateBackups(path, mode, cfg.backups)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, buf.Bytes(), mode, cfg.sync)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames it to path,
+// so path always contains either the old or the new content
+func writeFileAtomic(path string, data []byte, mode os.FileMode, sync bool) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create config file %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	_, err = f.Write(data)
+	if err != nil {
+		return fmt.Errorf("could not write config file %s: %w", path, err)
+	}
+
+	err = f.Chmod(mode)
+	if err != nil {
+		return fmt.Errorf("could not set mode of config file %s: %w", path, err)
+	}
+
+	if sync {
+		err = f.Sync()
+		if err != nil {
+			return fmt.Errorf("could not sync config file %s: %w", path, err)
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not write config file %s: %w", path, err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fmt.Errorf("could not replace config file %s: %w", path, err)
+	}
+
+	if sync {
+		err = syncDir(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("could not sync directory of config file %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// syncDir flushes a directory to disk so a rename in it is persisted, directories can't be synced on Windows
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// rotateBackups shifts path.1 ... path.n-1 up by one and copies the current file to path.1
+func rotateBackups(path string, mode os.FileMode, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	for i := n - 1; i >= 1; i-- {
+		err := os.Rename(backupName(path, i), backupName(path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not rotate config backup %s: %w", backupName(path, i), err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not open config file %s: %w", path, err)
+	}
+
+	return writeFileAtomic(backupName(path, 1), b, mode, false)
+}
+
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+type yamlDocument struct {
+	doc    *yaml.Node
+	indent int
+}
+
+// readYamlDocument reads an existing YAML file as node tree, found is false if the file doesn't exist or is empty
+func readYamlDocument(path string) (yamlDocument, bool, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return yamlDocument{}, false, nil
+	}
+	if err != nil {
+		return yamlDocument{}, false, fmt.Errorf("could not open config file %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return yamlDocument{}, false, fmt.Errorf("could not decode yaml config file %s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return yamlDocument{}, false, nil
+	}
+
+	return yamlDocument{doc: &doc, indent: detectYamlIndent(b)}, true, nil
+}
+
+// detectYamlIndent returns the indentation of the first indented key in a YAML file
+func detectYamlIndent(b []byte) int {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		trimmed := strings.TrimLeft(string(line), " ")
+		indent := len(line) - len(trimmed)
+		if indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "-") {
+			return indent
+		}
+	}
+
+	return defaultYamlIndent
+}
+
+// mergeYamlNodes updates existing with the values of updated in place, comments and keys that only
+// exist in existing are kept. If appendable is set, only keys found in appendable are added to existing.
+// Encrypted values of existing are never replaced by plain text, encryptionKey is used to compare and encrypt them.
+func mergeYamlNodes(existing *yaml.Node, updated *yaml.Node, appendable *yaml.Node, encryptionKey []byte) error {
+	existing = documentContent(existing)
+	updated = documentContent(updated)
+
+	switch {
+	case existing.Kind == yaml.MappingNode && updated.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			key, value := updated.Content[i], updated.Content[i+1]
+
+			var appendableValue *yaml.Node
+			if appendable != nil {
+				appendableValue = mappingValue(appendable, key.Value)
+			}
+
+			existingValue := mappingValue(existing, key.Value)
+			switch {
+			case existingValue != nil:
+				if appendable != nil && appendableValue == nil {
+					appendableValue = &yaml.Node{Kind: yaml.MappingNode}
+				}
+				if err := mergeYamlNodes(existingValue, value, appendableValue, encryptionKey); err != nil {
+					return err
+				}
+			case appendable == nil:
+				existing.Content = append(existing.Content, key, value)
+			case appendableValue != nil:
+				existing.Content = append(existing.Content, key, appendableValue)
+			}
+		}
+	case existing.Kind == yaml.SequenceNode && updated.Kind == yaml.SequenceNode:
+		for i := range updated.Content {
+			if i < len(existing.Content) {
+				if err := mergeYamlNodes(existing.Content[i], updated.Content[i], nil, encryptionKey); err != nil {
+					return err
+				}
+			} else {
+				existing.Content = append(existing.Content, updated.Content[i])
+			}
+		}
+		if len(existing.Content) > len(updated.Content) {
+			existing.Content = existing.Content[:len(updated.Content)]
+		}
+	case existing.Kind == yaml.ScalarNode && updated.Kind == yaml.ScalarNode:
+		if existing.Value == updated.Value && existing.ShortTag() == updated.ShortTag() {
+			return nil
+		}
+		if isEncryptedNode(existing) && updated.Value != "" && updated.ShortTag() != "!!null" {
+			return mergeEncryptedScalar(existing, updated, encryptionKey)
+		}
+		existing.Value = updated.Value
+		existing.Tag = updated.Tag
+		existing.Style = updated.Style
+	default:
+		// the kind changed, the node is replaced but its comments are kept
+		head, line, foot := existing.HeadComment, existing.LineComment, existing.FootComment
+		*existing = *updated
+		existing.HeadComment, existing.LineComment, existing.FootComment = head, line, foot
+	}
+
+	return nil
+}
+
+// mergeEncryptedScalar keeps the encrypted existing value if its plain text equals updated,
+// otherwise the value of updated is written encrypted
+func mergeEncryptedScalar(existing *yaml.Node, updated *yaml.Node, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("line %d: value is encrypted but changed: %w", existing.Line, ErrNoEncryptionKey)
+	}
+
+	plain, err := DecryptValue(key, existing.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", existing.Line, err)
+	}
+	// updated is encrypted already if it is a secret saved with WithEncryptedSecrets
+	updatedPlain, err := DecryptValue(key, updated.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", existing.Line, err)
+	}
+	if plain == updatedPlain {
+		return nil
+	}
+
+	encrypted := updated.Value
+	if !isEncryptedNode(updated) {
+		encrypted, err = EncryptValue(key, updated.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", existing.Line, err)
+		}
+	}
+	existing.Value = encrypted
+	existing.Tag = "!!str"
+
+	return nil
+}
+
+// documentContent returns the root node of a document node
+func documentContent(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+
+	return node
+}
+
+// mappingValue returns the value node of key in a mapping node or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = documentContent(node)
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// changedNode encodes c and removes all keys whose value equals the value in defaults,
+// if defaults is nil the default tags of c are used
+func changedNode(c interface{}, defaults interface{}) (*yaml.Node, error) {
+	if defaults == nil {
+		var err error
+		defaults, err = tagDefaults(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var node, defaultNode yaml.Node
+	err := node.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	err = defaultNode.Encode(defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	removeDefaultNodes(&node, &defaultNode)
+	return &node, nil
+}
+
+// removeDefaultNodes removes all mapping keys from node that have the same value in defaults,
+// nested mappings without any changed key are removed as well
+func removeDefaultNodes(node *yaml.Node, defaults *yaml.Node) {
+	node = documentContent(node)
+	defaults = documentContent(defaults)
+	if node.Kind != yaml.MappingNode || defaults.Kind != yaml.MappingNode {
+		return
+	}
+
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		defaultValue := mappingValue(defaults, key.Value)
+		if defaultValue != nil {
+			if yamlNodesEqual(value, defaultValue) {
+				continue
+			}
+			if value.Kind == yaml.MappingNode {
+				removeDefaultNodes(value, defaultValue)
+				if len(value.Content) == 0 {
+					continue
+				}
+			}
+		}
+
+		content = append(content, key, value)
+	}
+	node.Content = content
+}
+
+func yamlNodesEqual(a *yaml.Node, b *yaml.Node) bool {
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode && (a.Value != b.Value || a.ShortTag() != b.ShortTag()) {
+		return false
+	}
+
+	for i := range a.Content {
+		if !yamlNodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tagDefaults returns a new instance of the struct type of c with all default tags applied
+func tagDefaults(c interface{}) (interface{}, error) {
+	t := reflect.TypeOf(c)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct, got %v", t)
+	}
+
+	value := reflect.New(t).Elem()
+	err := applyDefaultTags(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.Interface(), nil
+}
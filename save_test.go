@@ -0,0 +1,296 @@
+package configstruct
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type saveTestConfig struct {
+	Hostname string   `yaml:"hostname"`
+	Port     int      `yaml:"port"`
+	Tags     []string `yaml:"tags"`
+	Log      struct {
+		Level string `yaml:"level"`
+	} `yaml:"log"`
+}
+
+func TestSave_PreserveExisting(t *testing.T) {
+	t.Run("keeps comments, ordering and unknown keys", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", `# main config
+port: 8080 # listen port
+# the hostname
+hostname: localhost
+extra: keep me
+log:
+  # log level
+  level: info
+tags:
+  - a
+  - b
+`)
+
+		conf := saveTestConfig{Hostname: "example.com", Port: 8080, Tags: []string{"a"}}
+		conf.Log.Level = "debug"
+
+		err := Save(path, &conf, WithPreserveExisting())
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, `# main config
+port: 8080 # listen port
+# the hostname
+hostname: example.com
+extra: keep me
+log:
+  # log level
+  level: debug
+tags:
+  - a
+`, string(b))
+	})
+
+	t.Run("appends new keys", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "# only host\nhostname: localhost\n")
+
+		err := Save(path, &saveTestConfig{Hostname: "localhost", Port: 9000}, WithPreserveExisting())
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), "# only host\nhostname: localhost\nport: 9000\n")
+	})
+
+	t.Run("missing file is created", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "new.yaml")
+
+		err := Save(path, &saveTestConfig{Hostname: "localhost"}, WithPreserveExisting())
+		assert.NoError(t, err)
+
+		conf := saveTestConfig{}
+		err = ParseWithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError), []string{"test"}, &conf, WithYamlConfig(path))
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", conf.Hostname)
+	})
+
+	t.Run("invalid existing file", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "hostname: [\n")
+
+		err := Save(path, &saveTestConfig{}, WithPreserveExisting())
+		assert.Error(t, err)
+	})
+}
+
+func TestSave_Atomic(t *testing.T) {
+	t.Run("default file modes", func(t *testing.T) {
+		type secretConfig struct {
+			Hostname string `yaml:"hostname"`
+			Password string `yaml:"password" secret:"true"`
+		}
+
+		dir := t.TempDir()
+
+		plain := filepath.Join(dir, "plain.yaml")
+		err := Save(plain, &saveTestConfig{Hostname: "localhost"})
+		assert.NoError(t, err)
+		info, err := os.Stat(plain)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+		secret := filepath.Join(dir, "secret.yaml")
+		err = Save(secret, &secretConfig{Password: "secret"}, WithSync())
+		assert.NoError(t, err)
+		info, err = os.Stat(secret)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("existing mode is kept", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "hostname: localhost\n")
+		assert.NoError(t, os.Chmod(path, 0640))
+
+		err := Save(path, &saveTestConfig{Hostname: "example.com"})
+		assert.NoError(t, err)
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("explicit mode wins", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "hostname: localhost\n")
+		assert.NoError(t, os.Chmod(path, 0640))
+
+		err := Save(path, &saveTestConfig{Hostname: "example.com"}, WithFileMode(0600))
+		assert.NoError(t, err)
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("existing file with secrets is restricted", func(t *testing.T) {
+		type secretConfig struct {
+			Password string `yaml:"password" secret:"true"`
+		}
+
+		path := writeTestFile(t, "config.yaml", "password: old\n")
+		assert.NoError(t, os.Chmod(path, 0644))
+
+		err := Save(path, &secretConfig{Password: "new"}, WithBackups(1))
+		assert.NoError(t, err)
+
+		for _, name := range []string{path, path + ".1"} {
+			info, err := os.Stat(name)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+	})
+
+	t.Run("symlinks are kept", func(t *testing.T) {
+		target := writeTestFile(t, "target.yaml", "hostname: localhost\n")
+		link := filepath.Join(filepath.Dir(target), "link.yaml")
+		assert.NoError(t, os.Symlink(target, link))
+
+		err := Save(link, &saveTestConfig{Hostname: "example.com"}, WithSync())
+		assert.NoError(t, err)
+
+		info, err := os.Lstat(link)
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink)
+
+		b, err := os.ReadFile(target)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), "hostname: example.com")
+	})
+
+	t.Run("no temp files are left", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		err := Save(path, &saveTestConfig{Hostname: "localhost"})
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("rotated backups", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		for _, host := range []string{"one", "two", "three", "four"} {
+			err := Save(path, &saveTestConfig{Hostname: host}, WithBackups(2))
+			assert.NoError(t, err)
+		}
+
+		for name, host := range map[string]string{path: "four", path + ".1": "three", path + ".2": "two"} {
+			b, err := os.ReadFile(name)
+			assert.NoError(t, err)
+			assert.Contains(t, string(b), "hostname: "+host)
+		}
+		assert.NoFileExists(t, path+".3")
+	})
+}
+
+func TestSave_OnlyChanged(t *testing.T) {
+	type defaultsConfig struct {
+		Hostname string `yaml:"hostname" default:"localhost"`
+		Port     int    `yaml:"port" default:"8080"`
+		Debug    bool   `yaml:"debug"`
+		Log      struct {
+			Level string `yaml:"level" default:"info"`
+			File  string `yaml:"file"`
+		} `yaml:"log"`
+	}
+
+	t.Run("default tags", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		conf := defaultsConfig{Hostname: "localhost", Port: 9000}
+		conf.Log.Level = "info"
+		err := Save(path, &conf, WithOnlyChanged())
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "port: 9000\n", string(b))
+	})
+
+	t.Run("defaults instance", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		conf := defaultsConfig{Hostname: "example.com", Port: 8080}
+		conf.Log.File = "app.log"
+		err := Save(path, &conf, WithDefaults(&defaultsConfig{Hostname: "example.com"}))
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "port: 8080\nlog:\n    file: app.log\n", string(b))
+	})
+
+	t.Run("preserve existing", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "# custom port\nport: 9000\n")
+
+		conf := defaultsConfig{Hostname: "localhost", Port: 8080, Debug: true}
+		conf.Log.Level = "info"
+		err := Save(path, &conf, WithOnlyChanged(), WithPreserveExisting())
+		assert.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# custom port\nport: 8080\ndebug: true\n", string(b))
+	})
+}
+
+func TestNewConfigCommand_Save(t *testing.T) {
+	var out bytes.Buffer
+
+	conf := saveTestConfig{Hostname: "localhost"}
+	cmd := NewCommand("", "Test CLI", &conf, nil, NewConfigCommand(&conf)).SetOutput(&out)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	conf.Port = 9000
+
+	err := cmd.ParseAndRun([]string{"cliName", "config", "save", path})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "config saved to")
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "port: 9000\n", string(b))
+
+	cmd = NewCommand("", "Test CLI", &conf, nil, NewConfigCommand(&conf)).SetOutput(&out)
+	err = cmd.ParseAndRun([]string{"cliName", "config", "save", "-all", path})
+	assert.NoError(t, err)
+
+	b, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "hostname: localhost\n")
+
+	t.Run("default tags are no changes", func(t *testing.T) {
+		type defaultsConfig struct {
+			Hostname string `yaml:"hostname" cli:"hostname"`
+			Port     int    `yaml:"port" default:"8080"`
+			Level    string `yaml:"level" default:"info"`
+		}
+
+		conf := defaultsConfig{}
+		cmd := NewCommand("", "Test CLI", &conf, nil, NewConfigCommand(&conf)).SetOutput(io.Discard)
+
+		err := cmd.ParseAndRun([]string{"cliName", "-hostname", "example.com", "config", "save", path})
+		assert.NoError(t, err)
+		assert.Equal(t, 8080, conf.Port)
+		assert.Equal(t, "info", conf.Level)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "hostname: example.com\n", string(b))
+	})
+}
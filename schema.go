@@ -0,0 +1,319 @@
+package configstruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON Schema that is generated for config structs
+type jsonSchema struct {
+	Schema               string            `json:"$schema,omitempty"`
+	Type                 string            `json:"type,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Default              interface{}       `json:"default,omitempty"`
+	Enum                 []interface{}     `json:"enum,omitempty"`
+	Properties           *schemaProperties `json:"properties,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+	Items                *jsonSchema       `json:"items,omitempty"`
+	AdditionalProperties interface{}       `json:"additionalProperties,omitempty"`
+}
+
+// schemaProperties keeps the properties in the order of the struct fields
+type schemaProperties struct {
+	keys   []string
+	values map[string]*jsonSchema
+}
+
+func (p *schemaProperties) set(key string, schema *jsonSchema) {
+	if _, ok := p.values[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.values[key] = schema
+}
+
+func (p *schemaProperties) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(p.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// GenerateJSONSchema writes a JSON Schema (draft 2020-12) for the YAML config file of the struct c to w.
+// Keys are taken from yaml tags, descriptions from usage tags, defaults from default tags or the values of c,
+// required fields from required tags and allowed values from oneof tags
+func GenerateJSONSchema(w io.Writer, c interface{}) error {
+	valueRef := reflect.ValueOf(c)
+	if valueRef.Kind() == reflect.Ptr {
+		valueRef = valueRef.Elem()
+	}
+	if valueRef.Kind() != reflect.Struct {
+		return fmt.Errorf("config type %s is not a struct", valueRef.Type())
+	}
+
+	schema, err := structSchema(valueRef)
+	if err != nil {
+		return err
+	}
+	schema.Schema = jsonSchemaDraft
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode json schema: %w", err)
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func structSchema(value reflect.Value) (*jsonSchema, error) {
+	// config files are decoded into the struct, so keys without a field are not allowed
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           &schemaProperties{values: make(map[string]*jsonSchema)},
+		AdditionalProperties: false,
+	}
+
+	err := addStructProperties(schema, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// addStructProperties adds all fields of the struct value as properties to schema, inline structs are merged
+func addStructProperties(schema *jsonSchema, value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldValue := value.Field(i)
+
+		if isInlineField(field) {
+			if err := addStructProperties(schema, reflect.Indirect(fieldValue)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+
+		property, err := typeSchema(field.Type, fieldValue)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		property.Description = field.Tag.Get("usage")
+
+		def, err := fieldDefault(field, fieldValue)
+		if err != nil {
+			return err
+		}
+		if def != nil && property.Type != "object" && !isSecretField(field) {
+			property.Default = def
+		}
+
+		for _, v := range strings.Fields(field.Tag.Get("oneof")) {
+			enumValue, err := parseTagValue(v, field.Type)
+			if err != nil {
+				return fmt.Errorf("invalid oneof value %s for field %s: %w", v, field.Name, err)
+			}
+			property.Enum = append(property.Enum, enumValue)
+		}
+
+		if isRequiredField(field) {
+			schema.Required = append(schema.Required, key)
+		}
+
+		schema.Properties.set(key, property)
+	}
+
+	return nil
+}
+
+// typeSchema returns the schema for a type, value is used for defaults of nested structs and may be invalid
+func typeSchema(t reflect.Type, value reflect.Value) (*jsonSchema, error) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return &jsonSchema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if value.IsValid() && !value.IsNil() {
+			return typeSchema(t.Elem(), value.Elem())
+		}
+		return typeSchema(t.Elem(), reflect.Value{})
+	case reflect.String:
+		return &jsonSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem(), reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s not supported", t.Key())
+		}
+		values, err := typeSchema(t.Elem(), reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if !value.IsValid() {
+			value = reflect.New(t).Elem()
+		}
+		return structSchema(value)
+	case reflect.Interface:
+		return &jsonSchema{}, nil
+	}
+
+	return nil, fmt.Errorf("type %s not supported", t)
+}
+
+// isInlineField reports whether the fields of a struct field are inlined in the YAML representation
+func isInlineField(field reflect.StructField) bool {
+	for _, flag := range strings.Split(field.Tag.Get("yaml"), ",")[1:] {
+		if flag == "inline" {
+			return field.Type.Kind() == reflect.Struct ||
+				(field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct)
+		}
+	}
+
+	return false
+}
+
+// isRequiredField reports whether a field is tagged as required, positional arguments are not part of the config file
+func isRequiredField(field reflect.StructField) bool {
+	return field.Tag.Get("required") == "true" && field.Tag.Get("arg") == ""
+}
+
+// fieldDefault returns the default of a field taken from its default tag or its non-zero value, nil if there is none
+func fieldDefault(field reflect.StructField, value reflect.Value) (interface{}, error) {
+	if tag, ok := field.Tag.Lookup("default"); ok {
+		def, err := parseTagValue(tag, field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default value for field %s: %w", field.Name, err)
+		}
+		return def, nil
+	}
+
+	if !value.IsValid() || value.IsZero() {
+		return nil, nil
+	}
+	if d, ok := value.Interface().(time.Duration); ok {
+		return d.String(), nil
+	}
+
+	switch reflect.Indirect(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		// convert to generic values so that nested keys match the yaml tags
+		return yamlGeneric(value.Interface())
+	}
+
+	return value.Interface(), nil
+}
+
+// yamlGeneric converts a value to its generic YAML representation of maps, slices and scalars
+func yamlGeneric(value interface{}) (interface{}, error) {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := yaml.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+
+	return generic, nil
+}
+
+// parseTagValue converts the string value of a tag to the given type
+func parseTagValue(value string, t reflect.Type) (interface{}, error) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(value, 0, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 0, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	}
+
+	target := reflect.New(t)
+	if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return nil, err
+	}
+
+	return target.Elem().Interface(), nil
+}
+
+// decodeTagValue sets the settable value to the string value of a tag
+func decodeTagValue(tag string, value reflect.Value) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(tag)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	parsed, err := parseTagValue(tag, value.Type())
+	if err != nil {
+		return err
+	}
+
+	value.Set(reflect.ValueOf(parsed).Convert(value.Type()))
+	return nil
+}
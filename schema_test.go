@@ -0,0 +1,89 @@
+package configstruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaNested struct {
+	Level string `yaml:"level" oneof:"debug info error" default:"info" usage:"log level"`
+}
+
+type schemaConfig struct {
+	Hostname  string            `yaml:"host" usage:"hostname value" required:"true" fileRequired:"true"`
+	Port      int               `yaml:"port_num" default:"8000"`
+	Debug     bool              `yaml:"debug_mode"`
+	Ratio     float64           `yaml:"ratio" required:"true"`
+	Endpoints []endpoint        `yaml:"endpoints" usage:"configured endpoints"`
+	Log       schemaNested      `yaml:"log"`
+	Labels    map[string]string `yaml:"labels"`
+	Skipped   string            `yaml:"-"`
+	Filename  string            `arg:"1" name:"filename" required:"true"`
+	internal  string
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	var out bytes.Buffer
+
+	conf := schemaConfig{
+		Hostname:  "localhost",
+		Endpoints: []endpoint{{User: "u1", URL: "https://a"}},
+	}
+
+	err := GenerateJSONSchema(&out, &conf)
+	assert.NoError(t, err)
+
+	expected := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "host": {"type": "string", "description": "hostname value", "default": "localhost"},
+    "port_num": {"type": "integer", "default": 8000},
+    "debug_mode": {"type": "boolean"},
+    "ratio": {"type": "number"},
+    "endpoints": {
+      "type": "array",
+      "description": "configured endpoints",
+      "default": [{"user": "u1", "pass": "", "url": "https://a"}],
+      "items": {
+        "type": "object",
+        "properties": {
+          "user": {"type": "string"},
+          "pass": {"type": "string"},
+          "url": {"type": "string"}
+        },
+        "additionalProperties": false
+      }
+    },
+    "log": {
+      "type": "object",
+      "properties": {
+        "level": {"type": "string", "description": "log level", "default": "info", "enum": ["debug", "info", "error"]}
+      },
+      "additionalProperties": false
+    },
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "filename": {"type": "string"}
+  },
+  "required": ["host", "ratio"],
+  "additionalProperties": false
+}`
+	assert.JSONEq(t, expected, out.String())
+
+	// properties keep the order of the struct fields
+	assert.Regexp(t, `(?s)"host".*"port_num".*"debug_mode".*"ratio".*"endpoints".*"log".*"labels"`, out.String())
+	assert.True(t, json.Valid(out.Bytes()))
+}
+
+func TestGenerateJSONSchema_InvalidType(t *testing.T) {
+	err := GenerateJSONSchema(&bytes.Buffer{}, "config")
+	assert.Error(t, err)
+
+	err = GenerateJSONSchema(&bytes.Buffer{}, &struct {
+		Handler func() `yaml:"handler"`
+	}{})
+	assert.Error(t, err)
+}
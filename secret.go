@@ -0,0 +1,107 @@
+package configstruct
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretMask replaces the value of fields tagged with secret:"true" in help, docs and Redacted
+const secretMask = "******"
+
+// Redacted returns the config struct c as YAML with the values of all fields tagged with secret:"true" masked,
+// so the effective config can be logged safely. Encoding errors are returned as text.
+func Redacted(c interface{}) string {
+	var node yaml.Node
+	err := node.Encode(c)
+	if err != nil {
+		return fmt.Sprintf("could not encode config: %v", err)
+	}
+
+	visitSecretNodes(&node, reflect.TypeOf(c), func(mapping *yaml.Node, i int) {
+		value := mapping.Content[i+1]
+		if value.Kind == yaml.ScalarNode && (value.Value == "" || value.ShortTag() == "!!null") {
+			return
+		}
+		mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: secretMask}
+	})
+
+	b, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprintf("could not encode config: %v", err)
+	}
+
+	return string(b)
+}
+
+// removeSecretNodes removes the keys of all fields tagged with secret:"true" from the encoded node of a value of type t
+func removeSecretNodes(node *yaml.Node, t reflect.Type) {
+	visitSecretNodes(node, t, func(mapping *yaml.Node, i int) {
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+	})
+}
+
+// visitSecretNodes calls fn with the mapping node and key index of every secret field in the encoded node of
+// a value of type t, keys are visited in reverse order so fn may remove them
+func visitSecretNodes(node *yaml.Node, t reflect.Type, fn func(mapping *yaml.Node, i int)) {
+	node = documentContent(node)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := len(node.Content) - 2; i >= 0; i -= 2 {
+			field, ok := fields[node.Content[i].Value]
+			if !ok {
+				continue
+			}
+			if isSecretField(field) {
+				fn(node, i)
+				continue
+			}
+			visitSecretNodes(node.Content[i+1], field.Type, fn)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			visitSecretNodes(item, t.Elem(), fn)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			visitSecretNodes(node.Content[i], t.Elem(), fn)
+		}
+	}
+}
+
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true"
+}
+
+// hasSecretFields reports whether a struct type contains a field tagged with secret:"true"
+func hasSecretFields(t reflect.Type) bool {
+	return hasSecretFieldsSeen(t, map[reflect.Type]bool{})
+}
+
+func hasSecretFieldsSeen(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isSecretField(field) || hasSecretFieldsSeen(field.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
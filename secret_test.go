@@ -0,0 +1,95 @@
+package configstruct
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretEndpoint struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token" secret:"true"`
+}
+
+type secretConfig struct {
+	Hostname  string           `cli:"hostname" yaml:"hostname" usage:"hostname value"`
+	Password  string           `cli:"password" yaml:"password" usage:"database password" secret:"true"`
+	Empty     string           `cli:"empty" yaml:"empty" secret:"true"`
+	Endpoints []secretEndpoint `yaml:"endpoints"`
+}
+
+func newSecretConfig() secretConfig {
+	return secretConfig{
+		Hostname:  "localhost",
+		Password:  "hunter2",
+		Endpoints: []secretEndpoint{{Name: "api", Token: "abc"}},
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	conf := newSecretConfig()
+
+	assert.Equal(t, `hostname: localhost
+password: '******'
+empty: ""
+endpoints:
+    - name: api
+      token: '******'
+`, Redacted(&conf))
+	assert.Equal(t, "hunter2", conf.Password)
+}
+
+func TestSecret_Usage(t *testing.T) {
+	t.Run("print defaults", func(t *testing.T) {
+		var out bytes.Buffer
+
+		conf := newSecretConfig()
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.SetOutput(&out)
+		err := ParseWithFlagSet(flagSet, []string{"test"}, &conf)
+		assert.NoError(t, err)
+
+		flagSet.PrintDefaults()
+		assert.NotContains(t, out.String(), "hunter2")
+		assert.Contains(t, out.String(), `database password (default "******")`)
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var out bytes.Buffer
+
+		conf := newSecretConfig()
+		cmd := NewCommand("", "Test CLI", &conf, nil).SetOutput(&out)
+		cmd.printUsage(&out)
+		assert.NotContains(t, out.String(), "hunter2")
+		assert.Contains(t, out.String(), "database password (default ******)")
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		var out bytes.Buffer
+
+		conf := newSecretConfig()
+		err := GenerateSample(&out, &conf, FormatYAML)
+		assert.NoError(t, err)
+		assert.NotContains(t, out.String(), "hunter2")
+		assert.NotContains(t, out.String(), "abc")
+	})
+}
+
+func TestSave_WithoutSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	conf := newSecretConfig()
+	err := Save(path, &conf, WithoutSecrets())
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `hostname: localhost
+endpoints:
+    - name: api
+`, string(b))
+}
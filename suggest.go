@@ -0,0 +1,91 @@
+package configstruct
+
+import (
+	"sort"
+	"strings"
+)
+
+// suggest returns all candidates that are close to name, ordered by their edit distance
+func suggest(name string, candidates []string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+
+	maxDistance := len(name) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	matches := make([]match, 0)
+	for _, candidate := range candidates {
+		if candidate == name || candidate == "" {
+			continue
+		}
+
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance <= maxDistance || strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(name)) {
+			matches = append(matches, match{candidate: candidate, distance: distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	suggestions := make([]string, 0, len(matches))
+	for _, m := range matches {
+		suggestions = append(suggestions, m.candidate)
+	}
+
+	return suggestions
+}
+
+// didYouMean formats suggestions as a hint, every suggestion is prefixed with the given prefix
+func didYouMean(suggestions []string, prefix string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		quoted = append(quoted, prefix+s)
+	}
+
+	return "did you mean " + strings.Join(quoted, " or ") + "?"
+}
+
+// levenshtein calculates the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
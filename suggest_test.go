@@ -0,0 +1,23 @@
+package configstruct
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("count", "count"))
+	assert.Equal(t, 2, levenshtein("cuont", "count"))
+	assert.Equal(t, 3, levenshtein("", "abc"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"count", "sum", "hostname", "port", "p"}
+
+	assert.Equal(t, []string{"count"}, suggest("cuont", candidates))
+	assert.Equal(t, []string{"hostname"}, suggest("hostnmae", candidates))
+	assert.Equal(t, []string{"count"}, suggest("cou", []string{"count", "remove"}))
+	assert.Empty(t, suggest("deploy", candidates))
+}
@@ -0,0 +1,328 @@
+package configstruct
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationIssue describes a single problem found in a config file
+type ValidationIssue struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", i.Line, i.Column, i.Message)
+	}
+
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Path, i.Message)
+}
+
+// ValidationError is returned by ValidateFile and contains all issues found in a config file
+type ValidationError struct {
+	File   string
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("config file %s is invalid:", e.File))
+	for _, issue := range e.Issues {
+		lines = append(lines, "  "+e.File+":"+issue.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ValidateFile checks a YAML config file against the config struct c without loading it into c.
+// Unknown keys, values that don't match the field types, missing required keys and values that are not
+// listed in a oneof tag are reported as *ValidationError with line and column numbers.
+// Encrypted values are decrypted if a key is set by option or env, otherwise their type is not checked
+func ValidateFile(path string, c interface{}, opts ...Option) error {
+	cfg := newConfig(opts...)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not open config file %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("could not decode yaml config file %s: %w", path, err)
+	}
+
+	key, err := resolveEncryptionKey(cfg.encryptionKey, cfg.encryptionKeyFile)
+	switch {
+	case err == nil:
+		if err := decryptNodes(&doc, key, ""); err != nil {
+			return fmt.Errorf("could not decrypt config file %s: %w", path, err)
+		}
+	case !errors.Is(err, ErrNoEncryptionKey):
+		return err
+	}
+
+	issues := validator{}.validateNode(&doc, reflect.TypeOf(c), "")
+	if len(issues) > 0 {
+		return &ValidationError{File: path, Issues: issues}
+	}
+
+	return nil
+}
+
+// validator checks YAML nodes against Go types
+type validator struct {
+	// unknownKeysOnly only reports unknown keys
+	unknownKeysOnly bool
+}
+
+// validateNode checks node against type t and returns all issues, path is the key path of the node
+func (v validator) validateNode(node *yaml.Node, t reflect.Type, path string) []ValidationIssue {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return v.validateNode(node.Content[0], t, path)
+	case yaml.AliasNode:
+		return v.validateNode(node.Alias, t, path)
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+
+	// encrypted values can't be checked without key
+	if isEncryptedNode(node) {
+		return nil
+	}
+
+	typeIssue := func() []ValidationIssue {
+		if v.unknownKeysOnly {
+			return nil
+		}
+
+		return []ValidationIssue{{
+			Path:    path,
+			Line:    node.Line,
+			Column:  node.Column,
+			Message: fmt.Sprintf("expected %s but got %s", typeName(t), nodeKindName(node)),
+		}}
+	}
+
+	if t == reflect.TypeOf(time.Duration(0)) {
+		var d time.Duration
+		if node.Kind != yaml.ScalarNode || node.Decode(&d) != nil {
+			return typeIssue()
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return typeIssue()
+		}
+		return v.validateMapping(node, t, path)
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return typeIssue()
+		}
+		issues := make([]ValidationIssue, 0)
+		for i, item := range node.Content {
+			issues = append(issues, v.validateNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return issues
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return typeIssue()
+		}
+		issues := make([]ValidationIssue, 0)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			issues = append(issues, v.validateNode(node.Content[i+1], t.Elem(), joinKeyPath(path, node.Content[i].Value))...)
+		}
+		return issues
+	}
+
+	if node.Kind != yaml.ScalarNode || node.Decode(reflect.New(t).Interface()) != nil {
+		return typeIssue()
+	}
+
+	return nil
+}
+
+// validateMapping checks the keys of a mapping node against the fields of struct type t
+func (v validator) validateMapping(node *yaml.Node, t reflect.Type, path string) []ValidationIssue {
+	fields := yamlFields(t)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	issues := make([]ValidationIssue, 0)
+	present := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		keyPath := joinKeyPath(path, keyNode.Value)
+
+		field, ok := fields[keyNode.Value]
+		if !ok {
+			message := "unknown key"
+			if hint := didYouMean(suggest(keyNode.Value, keys), ""); hint != "" {
+				message += ", " + hint
+			}
+			issues = append(issues, ValidationIssue{Path: keyPath, Line: keyNode.Line, Column: keyNode.Column, Message: message})
+			continue
+		}
+		present[keyNode.Value] = true
+
+		fieldIssues := v.validateNode(valueNode, field.Type, keyPath)
+		issues = append(issues, fieldIssues...)
+		if len(fieldIssues) > 0 {
+			continue
+		}
+
+		if v.unknownKeysOnly {
+			continue
+		}
+
+		if oneOf := strings.Fields(field.Tag.Get("oneof")); len(oneOf) > 0 && valueNode.Kind == yaml.ScalarNode {
+			if !containsString(oneOf, valueNode.Value) {
+				issues = append(issues, ValidationIssue{
+					Path:    keyPath,
+					Line:    valueNode.Line,
+					Column:  valueNode.Column,
+					Message: fmt.Sprintf("value %q is not one of %s", valueNode.Value, strings.Join(oneOf, ", ")),
+				})
+			}
+		}
+	}
+
+	for _, key := range sortedFieldKeys(t) {
+		if !v.unknownKeysOnly && isFileRequiredField(fields[key]) && !present[key] {
+			issues = append(issues, ValidationIssue{
+				Path:    joinKeyPath(path, key),
+				Line:    node.Line,
+				Column:  node.Column,
+				Message: "required key is missing",
+			})
+		}
+	}
+
+	return issues
+}
+
+// yamlFields returns all fields of struct type t by their YAML key, fields of inline structs are included
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isInlineField(field) {
+			inlineType := field.Type
+			if inlineType.Kind() == reflect.Ptr {
+				inlineType = inlineType.Elem()
+			}
+			for key, inlineField := range yamlFields(inlineType) {
+				fields[key] = inlineField
+			}
+			continue
+		}
+
+		if key := yamlKey(field); key != "" {
+			fields[key] = field
+		}
+	}
+
+	return fields
+}
+
+// sortedFieldKeys returns the YAML keys of struct type t in the order of the fields
+func sortedFieldKeys(t reflect.Type) []string {
+	keys := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isInlineField(field) {
+			inlineType := field.Type
+			if inlineType.Kind() == reflect.Ptr {
+				inlineType = inlineType.Elem()
+			}
+			keys = append(keys, sortedFieldKeys(inlineType)...)
+			continue
+		}
+
+		if key := yamlKey(field); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// isFileRequiredField reports whether the key of a field must be present in a config file, required:"true" alone is not
+// sufficient because such values can also be set by env or cli
+func isFileRequiredField(field reflect.StructField) bool {
+	return field.Tag.Get("fileRequired") == "true"
+}
+
+func joinKeyPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeName returns a readable name of a Go type as used in YAML
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "mapping"
+	case reflect.Slice, reflect.Array:
+		return "sequence"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+
+	return t.Kind().String()
+}
+
+// nodeKindName returns a readable description of a YAML node
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	}
+
+	return fmt.Sprintf("%q", node.Value)
+}
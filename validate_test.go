@@ -0,0 +1,103 @@
+package configstruct
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func TestValidateFile(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		path := writeTestFile(t, "valid.yaml", `host: localhost
+port_num: 8000
+endpoints:
+  - user: u1
+    url: https://a
+log:
+  level: debug
+labels:
+  env: prod
+`)
+		conf := schemaConfig{Hostname: "untouched"}
+
+		err := ValidateFile(path, &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, "untouched", conf.Hostname)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := writeTestFile(t, "invalid.yaml", `hots: localhost
+port_num: abc
+endpoints:
+  - user: u1
+    urls: https://a
+log:
+  level: verbose
+labels: [a, b]
+`)
+
+		err := ValidateFile(path, &schemaConfig{})
+
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []ValidationIssue{
+			{Path: "hots", Line: 1, Column: 1, Message: "unknown key, did you mean host?"},
+			{Path: "port_num", Line: 2, Column: 11, Message: `expected integer but got "abc"`},
+			{Path: "endpoints[0].urls", Line: 5, Column: 5, Message: "unknown key, did you mean url?"},
+			{Path: "log.level", Line: 7, Column: 10, Message: `value "verbose" is not one of debug, info, error`},
+			{Path: "labels", Line: 8, Column: 9, Message: "expected mapping but got sequence"},
+			{Path: "host", Line: 1, Column: 1, Message: "required key is missing"},
+		}, validationErr.Issues)
+		assert.Contains(t, err.Error(), path+":2:11: port_num: expected integer but got \"abc\"")
+	})
+
+	t.Run("required by env or cli only", func(t *testing.T) {
+		type Config struct {
+			Hostname string `env:"CONFIGSTRUCT_HOSTNAME" cli:"hostname" yaml:"host" required:"true"`
+			Port     int    `yaml:"port"`
+		}
+
+		path := writeTestFile(t, "overlay.yaml", "port: 8080\n")
+
+		err := ValidateFile(path, &Config{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		path := writeTestFile(t, "syntax.yaml", "host: [localhost\n")
+
+		err := ValidateFile(path, &schemaConfig{})
+		assert.Error(t, err)
+	})
+}
+
+func TestNewConfigCommand(t *testing.T) {
+	var out bytes.Buffer
+
+	path := writeTestFile(t, "valid.yaml", "host: localhost\n")
+	cmd := NewCommand("", "Test CLI", nil, nil, NewConfigCommand(&schemaConfig{})).SetOutput(&out)
+
+	err := cmd.ParseAndRun([]string{"cliName", "config", "validate", path})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "is valid")
+
+	path = writeTestFile(t, "invalid.yaml", "hots: localhost\n")
+	cmd = NewCommand("", "Test CLI", nil, nil, NewConfigCommand(&schemaConfig{})).SetOutput(&out)
+	err = cmd.ParseAndRun([]string{"cliName", "config", "validate", path})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}